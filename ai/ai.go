@@ -5,6 +5,8 @@ package ai
 import (
 	"context"
 	"errors"
+	"slices"
+	"strings"
 )
 
 // Error variables for common error cases.
@@ -29,10 +31,13 @@ type ModelNamer interface {
 
 // AnkiController defines the interface for AI API providers.
 type AnkiController interface {
-	// ChooseDeck selects a deck based on provided deck names and text.
-	ChooseDeck(ctx context.Context, deckNames []string, text string) (string, error)
+	// ChooseDeck ranks the decks that best fit the text, most likely first.
+	// Suggestions may name a deck that isn't in deckNames when none of them fit.
+	ChooseDeck(ctx context.Context, deckNames []string, text string) ([]DeckSuggestion, error)
 	// GenerateAnkiCards generates Anki cards for the given deck and text.
 	GenerateAnkiCards(ctx context.Context, deckName string, text string, prompt string) ([]AnkiCard, error)
+	// RefineAnkiCards revises a set of cards following the user's feedback and returns the complete revised set.
+	RefineAnkiCards(ctx context.Context, deckName string, cards []AnkiCard, feedback string, prompt string) ([]AnkiCard, error)
 	// ModelName returns the model name used by the AI API provider.
 	ModelName() ModelNamer
 }
@@ -58,8 +63,162 @@ func NewCardCreator(name APIProviderName, apiKey string, modelName ...ModelNamer
 	}
 }
 
+// DeckSuggestionCount is how many deck candidates ChooseDeck asks for.
+const DeckSuggestionCount = 3
+
+// DeckSuggestion is a candidate deck for a piece of text.
+type DeckSuggestion struct {
+	Deck       string  `json:"deck"`       // Full deck name.
+	Confidence float64 `json:"confidence"` // How well the deck fits, between 0 and 1.
+	Rationale  string  `json:"rationale"`  // Short reason the deck fits.
+}
+
+// RankDeckSuggestions sorts suggestions by confidence, most confident first. Suggestions without a deck
+// and repeated decks are dropped, and confidences are clamped between 0 and 1.
+func RankDeckSuggestions(suggestions []DeckSuggestion) []DeckSuggestion {
+	var ranked []DeckSuggestion
+	for _, s := range suggestions {
+		s.Deck = strings.TrimSpace(s.Deck)
+		if s.Deck == "" || slices.ContainsFunc(ranked, func(r DeckSuggestion) bool { return r.Deck == s.Deck }) {
+			continue
+		}
+		s.Confidence = min(max(s.Confidence, 0), 1)
+		ranked = append(ranked, s)
+	}
+	slices.SortStableFunc(ranked, func(a, b DeckSuggestion) int {
+		switch {
+		case a.Confidence > b.Confidence:
+			return -1
+		case a.Confidence < b.Confidence:
+			return 1
+		default:
+			return 0
+		}
+	})
+	return ranked
+}
+
+// CardKind is the kind of note a card should be stored as.
+type CardKind string
+
+// Supported card kinds.
+const (
+	CardKindBasic    CardKind = "basic"
+	CardKindReversed CardKind = "reversed"
+	CardKindCloze    CardKind = "cloze"
+)
+
 // AnkiCard represents a single Anki flashcard with a front and back side.
+// For cloze cards, Front holds the text with {{c1::...}} deletions and Back holds the extra information.
 type AnkiCard struct {
-	Front string // Front side of the card. `json:"front"`
-	Back  string // Back side of the card. `json:"back"`
+	Kind       CardKind `json:"kind"`       // Kind of card. Empty means basic.
+	Front      string   `json:"front"`      // Front side of the card.
+	Back       string   `json:"back"`       // Back side of the card.
+	Tags       []string `json:"tags"`       // Suggested tags for the note.
+	Extra      string   `json:"extra"`      // Optional context or mnemonic.
+	Source     string   `json:"source"`     // Quote from the input the card is based on.
+	Confidence float64  `json:"confidence"` // Self-rated confidence between 0 and 1.
+}
+
+// IsCloze reports whether the card is a cloze deletion card.
+func (c AnkiCard) IsCloze() bool {
+	return c.Kind == CardKindCloze
+}
+
+// ChangeKind describes what happened to a card between two versions of a card set.
+type ChangeKind string
+
+const (
+	ChangeUnchanged ChangeKind = "unchanged"
+	ChangeEdited    ChangeKind = "edited"
+	ChangeAdded     ChangeKind = "added"
+	ChangeRemoved   ChangeKind = "removed"
+)
+
+// CardChange is a single entry of a diff between two card sets. Old is nil for added cards and New is nil
+// for removed cards. Index is the card's position in the new set, or in the old set for removed cards.
+type CardChange struct {
+	Kind  ChangeKind
+	Index int
+	Old   *AnkiCard
+	New   *AnkiCard
+}
+
+// editedCardSimilarity is how alike two fronts must be for a card to count as edited rather than replaced.
+const editedCardSimilarity = 0.5
+
+// DiffCards compares two versions of a card set. Cards are matched by their front: identical fronts first,
+// then the most similar remaining ones. The changes are in the order of the new set, followed by removed cards.
+func DiffCards(before, after []AnkiCard) []CardChange {
+	matched := make([]int, len(after))
+	used := make([]bool, len(before))
+	for i := range after {
+		matched[i] = -1
+		for j := range before {
+			if !used[j] && before[j].Front == after[i].Front {
+				matched[i], used[j] = j, true
+				break
+			}
+		}
+	}
+	for i := range after {
+		if matched[i] >= 0 {
+			continue
+		}
+		best, bestScore := -1, editedCardSimilarity
+		for j := range before {
+			if score := wordSimilarity(before[j].Front, after[i].Front); !used[j] && score >= bestScore {
+				best, bestScore = j, score
+			}
+		}
+		if best >= 0 {
+			matched[i], used[best] = best, true
+		}
+	}
+
+	var changes []CardChange
+	for i := range after {
+		c := CardChange{Kind: ChangeAdded, Index: i, New: &after[i]}
+		if j := matched[i]; j >= 0 {
+			c.Old = &before[j]
+			c.Kind = ChangeEdited
+			if sameCard(before[j], after[i]) {
+				c.Kind = ChangeUnchanged
+			}
+		}
+		changes = append(changes, c)
+	}
+	for j := range before {
+		if !used[j] {
+			changes = append(changes, CardChange{Kind: ChangeRemoved, Index: j, Old: &before[j]})
+		}
+	}
+	return changes
+}
+
+func sameCard(a, b AnkiCard) bool {
+	return a.Kind == b.Kind && a.Front == b.Front && a.Back == b.Back && a.Extra == b.Extra &&
+		a.Source == b.Source && slices.Equal(a.Tags, b.Tags)
+}
+
+// wordSimilarity is the share of distinct lowercase words two texts have in common.
+func wordSimilarity(a, b string) float64 {
+	wa, wb := strings.Fields(strings.ToLower(a)), strings.Fields(strings.ToLower(b))
+	union := make(map[string]int)
+	for _, w := range wa {
+		union[w] |= 1
+	}
+	for _, w := range wb {
+		union[w] |= 2
+	}
+	if len(union) == 0 {
+		return 0
+	}
+	both := 0
+	for _, v := range union {
+		if v == 3 {
+			both++
+		}
+	}
+	return float64(both) / float64(len(union))
 }
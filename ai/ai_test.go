@@ -30,3 +30,91 @@ func Test_NewAICardCreator_Anthropic_Unimplemented_Fail(t *testing.T) {
 		}
 	}
 }
+
+func Test_RankDeckSuggestions(t *testing.T) {
+	ranked := ai.RankDeckSuggestions([]ai.DeckSuggestion{
+		{Deck: "Haki::Math", Confidence: 0.2},
+		{Deck: " Haki::Go ", Confidence: 0.9},
+		{Deck: "", Confidence: 1},
+		{Deck: "Haki::Go", Confidence: 0.5},
+		{Deck: "Haki::Networking", Confidence: 1.7},
+	})
+
+	want := []string{"Haki::Networking", "Haki::Go", "Haki::Math"}
+	if len(ranked) != len(want) {
+		t.Fatalf("Expected %d suggestions, got %d: %v", len(want), len(ranked), ranked)
+	}
+	for i, deck := range want {
+		if ranked[i].Deck != deck {
+			t.Errorf("Expected suggestion %d to be '%s', got '%s'", i, deck, ranked[i].Deck)
+		}
+	}
+	if ranked[0].Confidence != 1 {
+		t.Errorf("Expected confidence to be clamped to 1, got %v", ranked[0].Confidence)
+	}
+	if ranked[1].Confidence != 0.9 {
+		t.Errorf("Expected the first suggestion of a repeated deck to be kept, got %v", ranked[1].Confidence)
+	}
+}
+
+func Test_DiffCards(t *testing.T) {
+	before := []ai.AnkiCard{
+		{Front: "What is TCP?", Back: "A protocol."},
+		{Front: "What does TCP slow start do?", Back: "Grows the window."},
+		{Front: "What is a congestion window?", Back: "A limit."},
+	}
+	after := []ai.AnkiCard{
+		{Front: "What is TCP?", Back: "A protocol."},
+		{Front: "What does TCP slow start do exactly?", Back: "Doubles the window every RTT."},
+		{Front: "What is fast retransmit?", Back: "Resending after three duplicate ACKs."},
+	}
+
+	changes := ai.DiffCards(before, after)
+	want := []struct {
+		kind  ai.ChangeKind
+		index int
+	}{
+		{ai.ChangeUnchanged, 0},
+		{ai.ChangeEdited, 1},
+		{ai.ChangeAdded, 2},
+		{ai.ChangeRemoved, 2},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("Expected %d changes, got %d: %+v", len(want), len(changes), changes)
+	}
+	for i, w := range want {
+		if changes[i].Kind != w.kind || changes[i].Index != w.index {
+			t.Errorf("Expected change %d to be %s at %d, got %s at %d", i, w.kind, w.index, changes[i].Kind, changes[i].Index)
+		}
+	}
+	if changes[1].Old.Front != before[1].Front {
+		t.Errorf("Expected the edited card to be matched with '%s', got '%s'", before[1].Front, changes[1].Old.Front)
+	}
+}
+
+func Test_ApplyCritiques(t *testing.T) {
+	cards := []ai.AnkiCard{
+		{Front: "What is TCP?"},
+		{Front: "What are the phases of TCP congestion control?"},
+		{Front: "What is a vague question?"},
+	}
+	critiques := []ai.Critique{
+		{Index: 0, Scores: ai.Scores{MinimumInformation: 1, Unambiguous: 1, NoEnumeration: 1, BackLength: 1}},
+		{Index: 1, Rewrites: []ai.AnkiCard{{Front: "What is slow start?"}, {Front: "What is congestion avoidance?"}}},
+		{Index: 2, Rewrites: []ai.AnkiCard{{Front: "What is a precise question?"}}},
+	}
+
+	applied := ai.ApplyCritiques(cards, critiques)
+	want := []string{"What is TCP?", "What is slow start?", "What is congestion avoidance?", "What is a precise question?"}
+	if len(applied) != len(want) {
+		t.Fatalf("Expected %d cards, got %d", len(want), len(applied))
+	}
+	for i, front := range want {
+		if applied[i].Front != front {
+			t.Errorf("Expected card %d to be '%s', got '%s'", i, front, applied[i].Front)
+		}
+	}
+	if critiques[0].Scores.Average() != 1 {
+		t.Errorf("Expected an average score of 1, got %v", critiques[0].Scores.Average())
+	}
+}
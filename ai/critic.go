@@ -0,0 +1,193 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// Critic reviews generated cards against spaced repetition best practices.
+type Critic interface {
+	// CritiqueAnkiCards scores every card and suggests rewrites for the ones that break the rubric.
+	CritiqueAnkiCards(ctx context.Context, cards []AnkiCard) ([]Critique, error)
+}
+
+// Scores rate a card against each rule of the rubric, from 0 (breaks it) to 1 (follows it).
+type Scores struct {
+	MinimumInformation float64 `json:"minimum_information"` // The card asks for a single fact.
+	Unambiguous        float64 `json:"unambiguous"`         // The front has exactly one correct answer.
+	NoEnumeration      float64 `json:"no_enumeration"`      // The back isn't a list to memorize.
+	BackLength         float64 `json:"back_length"`         // The back is short enough to recall in one go.
+}
+
+// Average returns the mean of all scores.
+func (s Scores) Average() float64 {
+	return (s.MinimumInformation + s.Unambiguous + s.NoEnumeration + s.BackLength) / 4
+}
+
+// Critique is the critic's verdict on one card.
+type Critique struct {
+	Index  int      `json:"card"` // Position of the card in the critiqued set.
+	Scores Scores   `json:"scores"`
+	Issues []string `json:"issues"`
+	// Rewrites replace the card. One rewrite fixes it, several split it, none keep it as it is.
+	Rewrites []AnkiCard `json:"rewrites"`
+}
+
+// NeedsRewrite reports whether the critic suggested replacing the card.
+func (c Critique) NeedsRewrite() bool {
+	return len(c.Rewrites) > 0
+}
+
+// ApplyCritiques replaces each critiqued card with its suggested rewrites and keeps the other cards.
+func ApplyCritiques(cards []AnkiCard, critiques []Critique) []AnkiCard {
+	rewrites := make(map[int][]AnkiCard, len(critiques))
+	for _, c := range critiques {
+		if c.NeedsRewrite() {
+			rewrites[c.Index] = c.Rewrites
+		}
+	}
+
+	var applied []AnkiCard
+	for i, card := range cards {
+		if r, ok := rewrites[i]; ok {
+			applied = append(applied, r...)
+			continue
+		}
+		applied = append(applied, card)
+	}
+	return applied
+}
+
+const criticPrompt = `You are an expert in spaced repetition who reviews Anki cards before they are added to a deck.
+Score every card from 0 to 1 against this rubric:
+- minimum_information: the card asks for a single fact. Cards covering several facts score low.
+- unambiguous: the front has exactly one correct answer and can't be misread.
+- no_enumeration: the back isn't a list or set of items to memorize.
+- back_length: the back can be recalled in one go, ideally a sentence or a short phrase.
+List the problems you find as short issues. For every card that breaks a rule, suggest rewrites: one card to fix it,
+or several atomic cards to split it. Keep the card's kind, tags and HTML formatting. Leave rewrites empty for good cards.`
+
+// CritiqueAnkiCards uses the OpenAI API to score the cards against the rubric and suggest rewrites.
+func (s *OpenAICardCreator) CritiqueAnkiCards(ctx context.Context, cards []AnkiCard) ([]Critique, error) {
+	var current strings.Builder
+	for i, c := range cards {
+		data, err := json.Marshal(c)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&current, "Card %d: %s\n", i+1, data)
+	}
+
+	resp, err := s.client.createChatCompletion(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model:       s.ModelName().String(),
+			MaxTokens:   4096,
+			Temperature: 0.1,
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: criticPrompt,
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: current.String(),
+				},
+			},
+			Tools: []openai.Tool{cardCritiqueTool()},
+			ToolChoice: openai.ToolChoice{
+				Type: openai.ToolTypeFunction,
+				Function: openai.ToolFunction{
+					Name: "card_critique",
+				},
+			},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var data critiqueData
+	err = json.Unmarshal([]byte(resp.Choices[0].Message.ToolCalls[0].Function.Arguments), &data)
+	if err != nil {
+		return nil, err
+	}
+	return data.critiques(len(cards)), nil
+}
+
+type critiqueData struct {
+	Critiques []Critique `json:"critiques"`
+}
+
+// critiques converts the model's card numbers, which start at 1, to indexes and drops critiques of unknown cards.
+func (d critiqueData) critiques(cardCount int) []Critique {
+	var critiques []Critique
+	for _, c := range d.Critiques {
+		c.Index--
+		if c.Index < 0 || c.Index >= cardCount {
+			continue
+		}
+		critiques = append(critiques, c)
+	}
+	return critiques
+}
+
+func cardCritiqueTool() openai.Tool {
+	score := func(description string) jsonschema.Definition {
+		return jsonschema.Definition{Type: jsonschema.Number, Description: description}
+	}
+	return openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:   "card_critique",
+			Strict: true,
+			Parameters: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"critiques": {
+						Type: jsonschema.Array,
+						Items: &jsonschema.Definition{
+							Type: jsonschema.Object,
+							Properties: map[string]jsonschema.Definition{
+								"card": {
+									Type:        jsonschema.Integer,
+									Description: "The number of the card, starting at 1.",
+								},
+								"scores": {
+									Type: jsonschema.Object,
+									Properties: map[string]jsonschema.Definition{
+										"minimum_information": score("Whether the card asks for a single fact, from 0 to 1."),
+										"unambiguous":         score("Whether the front has exactly one correct answer, from 0 to 1."),
+										"no_enumeration":      score("Whether the back avoids lists to memorize, from 0 to 1."),
+										"back_length":         score("Whether the back is short enough to recall in one go, from 0 to 1."),
+									},
+									Required:             []string{"minimum_information", "unambiguous", "no_enumeration", "back_length"},
+									AdditionalProperties: false,
+								},
+								"issues": {
+									Type:        jsonschema.Array,
+									Items:       &jsonschema.Definition{Type: jsonschema.String},
+									Description: "Short descriptions of the rules the card breaks.",
+								},
+								"rewrites": {
+									Type:        jsonschema.Array,
+									Items:       ankiCardSchema(),
+									Description: "Cards to replace this one with. One to fix it, several to split it, none if it's good.",
+								},
+							},
+							Required:             []string{"card", "scores", "issues", "rewrites"},
+							AdditionalProperties: false,
+						},
+					},
+				},
+				Required:             []string{"critiques"},
+				AdditionalProperties: false,
+			},
+		},
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/sashabaranov/go-openai"
@@ -74,8 +75,8 @@ func (s *OpenAICardCreator) ModelName() ModelNamer {
 	return s.client.modelType
 }
 
-// ChooseDeck uses the OpenAI API to select a deck based on provided deck names and text.
-func (s *OpenAICardCreator) ChooseDeck(ctx context.Context, deckNames []string, text string) (string, error) {
+// ChooseDeck uses the OpenAI API to rank the decks that best fit the text.
+func (s *OpenAICardCreator) ChooseDeck(ctx context.Context, deckNames []string, text string) ([]DeckSuggestion, error) {
 	deckNameChoices := strings.Join(deckNames, ", ")
 
 	resp, err := s.client.createChatCompletion(
@@ -85,8 +86,9 @@ func (s *OpenAICardCreator) ChooseDeck(ctx context.Context, deckNames []string,
 			Messages: []openai.ChatCompletionMessage{
 				{
 					Role: openai.ChatMessageRoleSystem,
-					Content: "Please enter a string, and we will select the best Anki card deck to place it in: " +
-						"Deck: Pick the most reasonable choice from the following ```" + deckNameChoices + "```",
+					Content: "Please enter a string, and we will select the best Anki card decks to place it in: " +
+						fmt.Sprintf("Candidates: Rank up to %d of the most reasonable choices from the following ```", DeckSuggestionCount) + deckNameChoices + "```. " +
+						"Only suggest a new deck when none of them fit.",
 				},
 				{
 					Role:    openai.ChatMessageRoleUser,
@@ -102,11 +104,30 @@ func (s *OpenAICardCreator) ChooseDeck(ctx context.Context, deckNames []string,
 						Parameters: &jsonschema.Definition{
 							Type: jsonschema.Object,
 							Properties: map[string]jsonschema.Definition{
-								"Deck": {
-									Type: jsonschema.String,
+								"candidates": {
+									Type: jsonschema.Array,
+									Items: &jsonschema.Definition{
+										Type: jsonschema.Object,
+										Properties: map[string]jsonschema.Definition{
+											"deck": {
+												Type:        jsonschema.String,
+												Description: "The full deck name, including parent decks separated by '::'.",
+											},
+											"confidence": {
+												Type:        jsonschema.Number,
+												Description: "How well the deck fits the text, from 0 to 1.",
+											},
+											"rationale": {
+												Type:        jsonschema.String,
+												Description: "One short sentence on why the deck fits.",
+											},
+										},
+										Required:             []string{"deck", "confidence", "rationale"},
+										AdditionalProperties: false,
+									},
 								},
 							},
-							Required:             []string{"Deck"},
+							Required:             []string{"candidates"},
 							AdditionalProperties: false,
 						},
 					},
@@ -121,77 +142,74 @@ func (s *OpenAICardCreator) ChooseDeck(ctx context.Context, deckNames []string,
 		},
 	)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	var result = make(map[string]string)
-	err = json.Unmarshal([]byte(resp.Choices[0].Message.ToolCalls[0].Function.Arguments), &result)
+	var data chooseDeckData
+	err = json.Unmarshal([]byte(resp.Choices[0].Message.ToolCalls[0].Function.Arguments), &data)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	for _, key := range []string{"Deck"} {
-		if _, ok := result[key]; !ok {
-			return "", ErrMissingKey{Key: key}
-		}
+	if data.Candidates == nil {
+		return nil, ErrMissingKey{Key: "candidates"}
 	}
 
-	return result["Deck"], nil
+	return RankDeckSuggestions(data.Candidates), nil
+}
+
+type chooseDeckData struct {
+	Candidates []DeckSuggestion `json:"candidates"`
 }
 
-// Create uses the OpenAI API to generate AnkiCard's (front and back) for the given deck and text.
+// GenerateAnkiCards uses the OpenAI API to generate AnkiCard's (front and back) for the given deck and text.
 func (s *OpenAICardCreator) GenerateAnkiCards(ctx context.Context, deckName string, text string, prompt string) ([]AnkiCard, error) {
+	return s.createAnkiCards(ctx, []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: prompt,
+		},
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: text,
+		},
+	})
+}
+
+// RefineAnkiCards uses the OpenAI API to revise a set of cards following the user's feedback.
+func (s *OpenAICardCreator) RefineAnkiCards(ctx context.Context, deckName string, cards []AnkiCard, feedback string, prompt string) ([]AnkiCard, error) {
+	var current strings.Builder
+	for i, c := range cards {
+		data, err := json.Marshal(c)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&current, "Card %d: %s\n", i+1, data)
+	}
+
+	return s.createAnkiCards(ctx, []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: prompt,
+		},
+		{
+			Role: openai.ChatMessageRoleUser,
+			Content: "These are the current cards for the deck " + deckName + ":\n" + current.String() +
+				"\nRevise them following this feedback: " + feedback + "\n" +
+				"Return the complete revised set in the same order. Keep the cards the feedback doesn't mention exactly as they are.",
+		},
+	})
+}
 
+// createAnkiCards sends the messages and returns the cards the model created with the anki_card_creation tool.
+func (s *OpenAICardCreator) createAnkiCards(ctx context.Context, messages []openai.ChatCompletionMessage) ([]AnkiCard, error) {
 	resp, err := s.client.createChatCompletion(
 		ctx,
 		openai.ChatCompletionRequest{
 			Model:       s.ModelName().String(),
 			MaxTokens:   4096,
 			Temperature: 0.1,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: prompt,
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: text,
-				},
-			},
-			Tools: []openai.Tool{
-				{
-					Type: openai.ToolTypeFunction,
-					Function: &openai.FunctionDefinition{
-						Name:   "anki_card_creation",
-						Strict: true,
-						Parameters: &jsonschema.Definition{
-							Type: jsonschema.Object,
-							Properties: map[string]jsonschema.Definition{
-								"cards": {
-									Type: jsonschema.Array,
-									Items: &jsonschema.Definition{
-										Type: jsonschema.Object,
-										Properties: map[string]jsonschema.Definition{
-											"front": {
-												Type:        jsonschema.String,
-												Description: "The front side of the card. Example: 'What is the capital of France?'",
-											},
-											"back": {
-												Type:        jsonschema.String,
-												Description: "The back side of the card. Example: 'Paris'",
-											},
-										},
-										AdditionalProperties: false,
-										Required:             []string{"front", "back"},
-									},
-									AdditionalProperties: false,
-								},
-							},
-							Required:             []string{"cards"},
-							AdditionalProperties: false,
-						},
-					},
-				},
-			},
+			Messages:    messages,
+			Tools:       []openai.Tool{ankiCardCreationTool()},
 			ToolChoice: openai.ToolChoice{
 				Type: openai.ToolTypeFunction,
 				Function: openai.ToolFunction{
@@ -213,6 +231,70 @@ func (s *OpenAICardCreator) GenerateAnkiCards(ctx context.Context, deckName stri
 	return data.Cards, nil
 }
 
+// ankiCardCreationTool is the tool the model fills in with the cards it creates.
+func ankiCardCreationTool() openai.Tool {
+	return openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:   "anki_card_creation",
+			Strict: true,
+			Parameters: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"cards": {
+						Type:                 jsonschema.Array,
+						Items:                ankiCardSchema(),
+						AdditionalProperties: false,
+					},
+				},
+				Required:             []string{"cards"},
+				AdditionalProperties: false,
+			},
+		},
+	}
+}
+
+// ankiCardSchema describes a single card in the tools that return cards.
+func ankiCardSchema() *jsonschema.Definition {
+	return &jsonschema.Definition{
+		Type: jsonschema.Object,
+		Properties: map[string]jsonschema.Definition{
+			"kind": {
+				Type:        jsonschema.String,
+				Enum:        []string{string(CardKindBasic), string(CardKindReversed), string(CardKindCloze)},
+				Description: "The kind of card. Use 'cloze' for facts best learned by filling in a blank, 'reversed' when both sides should be asked, otherwise 'basic'.",
+			},
+			"front": {
+				Type:        jsonschema.String,
+				Description: "The front side of the card. Example: 'What is the capital of France?'. For cloze cards, the full text with deletions. Example: 'The capital of France is {{c1::Paris}}.'",
+			},
+			"back": {
+				Type:        jsonschema.String,
+				Description: "The back side of the card. Example: 'Paris'. For cloze cards, optional extra context shown after answering.",
+			},
+			"tags": {
+				Type:        jsonschema.Array,
+				Items:       &jsonschema.Definition{Type: jsonschema.String},
+				Description: "A few short lowercase tags describing the card's subject, without spaces. Example: ['geography', 'europe']",
+			},
+			"extra": {
+				Type:        jsonschema.String,
+				Description: "Optional context or mnemonic that helps remember the answer. Empty string if there is none.",
+			},
+			"source": {
+				Type:        jsonschema.String,
+				Description: "A verbatim quote from the user's input the card is based on. Empty string if the card isn't based on a quote.",
+			},
+			"confidence": {
+				Type:        jsonschema.Number,
+				Description: "How confident you are that the card is accurate, from 0 to 1.",
+			},
+		},
+		AdditionalProperties: false,
+		Required:             []string{"kind", "front", "back", "tags", "extra", "source", "confidence"},
+	}
+}
+
 type createAnkiCardsData struct {
 	Cards []AnkiCard `json:"cards"`
 }
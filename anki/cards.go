@@ -0,0 +1,38 @@
+package anki
+
+import "fmt"
+
+type CardService struct {
+	client *Client
+}
+
+func NewCardService(client *Client) *CardService {
+	return &CardService{client}
+}
+
+// CardsInfoParams contains the parameters for fetching card info
+type CardsInfoParams struct {
+	Cards []float64 `json:"cards"`
+}
+
+// CardInfo represents a card and its review statistics as returned by cardsInfo
+type CardInfo struct {
+	CardID   float64 `json:"cardId"`
+	NoteID   float64 `json:"note"`
+	DeckName string  `json:"deckName"`
+	// Factor is the ease factor in permille, e.g. 2500 for 250%.
+	Factor int `json:"factor"`
+	// Interval is the current interval in days. Negative values are in seconds, for cards in learning.
+	Interval int `json:"interval"`
+	Reps     int `json:"reps"`
+	Lapses   int `json:"lapses"`
+}
+
+// Info returns the review statistics of the cards with the given ids.
+func (svc *CardService) Info(ids ...float64) ([]CardInfo, error) {
+	var infos []CardInfo
+	if err := svc.client.sendAndUnmarshal("cardsInfo", CardsInfoParams{Cards: ids}, &infos); err != nil {
+		return nil, fmt.Errorf("cardsInfo: %w", err)
+	}
+	return infos, nil
+}
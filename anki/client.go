@@ -22,6 +22,8 @@ type AnkiClienter interface {
 	Notes() *NoteService
 	ModelNames() *ModelNameService
 	DeckNames() *DeckNameService
+	Media() *MediaService
+	Cards() *CardService
 }
 
 // Client represents an Anki API client.
@@ -35,6 +37,8 @@ type ClientServices struct {
 	Notes      *NoteService
 	ModelNames *ModelNameService
 	DeckNames  *DeckNameService
+	Media      *MediaService
+	Cards      *CardService
 }
 
 // requestResult represents the structure of the Anki API response.
@@ -58,6 +62,8 @@ func NewClient(baseURL string) *Client {
 		Notes:      NewNoteService(c),
 		ModelNames: NewModelNameService(c),
 		DeckNames:  NewDeckNameService(c),
+		Media:      NewMediaService(c),
+		Cards:      NewCardService(c),
 	}
 	return c
 }
@@ -77,6 +83,16 @@ func (c *Client) DeckNames() *DeckNameService {
 	return c.services.DeckNames
 }
 
+// Media returns the MediaService for the Anki API client.
+func (c *Client) Media() *MediaService {
+	return c.services.Media
+}
+
+// Cards returns the CardService for the Anki API client.
+func (c *Client) Cards() *CardService {
+	return c.services.Cards
+}
+
 // SetHTTPClient sets a custom HTTP client for the Anki API client.
 func (c *Client) SetHTTPClient(client *http.Client) *Client {
 	c.httpClient = client
@@ -38,6 +38,12 @@ func TestNewClient(t *testing.T) {
 			if client.DeckNames() == nil {
 				t.Error("NewClient() DeckNames service is nil")
 			}
+			if client.Media() == nil {
+				t.Error("NewClient() Media service is nil")
+			}
+			if client.Cards() == nil {
+				t.Error("NewClient() Cards service is nil")
+			}
 		})
 	}
 }
@@ -0,0 +1,53 @@
+package anki
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Names and fields of the note types that ship with Anki.
+const (
+	ModelBasic         = "Basic"
+	ModelBasicReversed = "Basic (and reversed card)"
+	ModelCloze         = "Cloze"
+
+	FieldFront     = "Front"
+	FieldBack      = "Back"
+	FieldText      = "Text"
+	FieldBackExtra = "Back Extra"
+)
+
+var (
+	ErrClozeMissingDeletion = errors.New("cloze text has no {{c1::...}} deletion")
+	ErrClozeUnbalanced      = errors.New("cloze text has unbalanced braces")
+	ErrClozeInvalidNumber   = errors.New("cloze deletion number must be a positive integer")
+	ErrClozeEmptyDeletion   = errors.New("cloze deletion is empty")
+)
+
+var clozeDeletionRegex = regexp.MustCompile(`\{\{c([^:{}]*)::(.*?)\}\}`)
+
+// ValidateCloze checks that text contains at least one well formed cloze deletion, e.g. {{c1::Paris::city}}.
+func ValidateCloze(text string) error {
+	if strings.Count(text, "{{") != strings.Count(text, "}}") {
+		return ErrClozeUnbalanced
+	}
+
+	matches := clozeDeletionRegex.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return ErrClozeMissingDeletion
+	}
+	for _, m := range matches {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 {
+			return fmt.Errorf("c%s: %w", m[1], ErrClozeInvalidNumber)
+		}
+		answer, _, _ := strings.Cut(m[2], "::")
+		if strings.TrimSpace(answer) == "" {
+			return fmt.Errorf("c%d: %w", n, ErrClozeEmptyDeletion)
+		}
+	}
+	return nil
+}
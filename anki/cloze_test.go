@@ -0,0 +1,34 @@
+package anki_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/netr/haki/anki"
+)
+
+func TestValidateCloze(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected error
+	}{
+		{"single deletion", "The capital of France is {{c1::Paris}}.", nil},
+		{"deletion with hint", "The capital of France is {{c1::Paris::city}}.", nil},
+		{"multiple deletions", "{{c1::Go}} was created at {{c2::Google}}.", nil},
+		{"no deletion", "The capital of France is Paris.", anki.ErrClozeMissingDeletion},
+		{"unbalanced braces", "The capital of France is {{c1::Paris}.", anki.ErrClozeUnbalanced},
+		{"zero number", "The capital of France is {{c0::Paris}}.", anki.ErrClozeInvalidNumber},
+		{"missing number", "The capital of France is {{c::Paris}}.", anki.ErrClozeInvalidNumber},
+		{"empty deletion", "The capital of France is {{c1::  }}.", anki.ErrClozeEmptyDeletion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := anki.ValidateCloze(tt.text)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("ValidateCloze(%q) = %v, want %v", tt.text, err, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package anki
+
+import "fmt"
+
+type MediaService struct {
+	client *Client
+}
+
+func NewMediaService(client *Client) *MediaService {
+	return &MediaService{client}
+}
+
+type DeleteMediaFileParams struct {
+	Filename string `json:"filename"`
+}
+
+// Delete removes a file from Anki's media collection.
+func (svc *MediaService) Delete(filename string) error {
+	if _, err := svc.client.Send("deleteMediaFile", DeleteMediaFileParams{Filename: filename}); err != nil {
+		return fmt.Errorf("deleteMediaFile: %w", err)
+	}
+	return nil
+}
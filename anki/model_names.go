@@ -33,3 +33,16 @@ func (svc *ModelNameService) GetNamesAndIds() (ModelNamesAndIds, error) {
 	}
 	return models, nil
 }
+
+type ModelFieldNamesParams struct {
+	ModelName string `json:"modelName"`
+}
+
+// FieldNames returns the field names of the given model, in the order they appear on the note.
+func (svc *ModelNameService) FieldNames(modelName string) ([]string, error) {
+	var fields []string
+	if err := svc.client.sendAndUnmarshal("modelFieldNames", ModelFieldNamesParams{ModelName: modelName}, &fields); err != nil {
+		return nil, fmt.Errorf("modelFieldNames: %w", err)
+	}
+	return fields, nil
+}
@@ -1,6 +1,9 @@
 package anki
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type NoteService struct {
 	client *Client
@@ -18,6 +21,141 @@ func (svc *NoteService) Add(note Note) (float64, error) {
 	return id, nil
 }
 
+// DeleteNotesParams contains the parameters for deleting notes
+type DeleteNotesParams struct {
+	Notes []float64 `json:"notes"`
+}
+
+// Delete removes the notes with the given ids, along with all of their cards.
+func (svc *NoteService) Delete(ids ...float64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := svc.client.Send("deleteNotes", DeleteNotesParams{Notes: ids}); err != nil {
+		return fmt.Errorf("deleteNotes: %w", err)
+	}
+	return nil
+}
+
+// FindNotesParams contains the parameters for searching notes
+type FindNotesParams struct {
+	Query string `json:"query"`
+}
+
+// Find returns the ids of the notes matching an Anki search query.
+func (svc *NoteService) Find(query string) ([]float64, error) {
+	var ids []float64
+	if err := svc.client.sendAndUnmarshal("findNotes", FindNotesParams{Query: query}, &ids); err != nil {
+		return nil, fmt.Errorf("findNotes: %w", err)
+	}
+	return ids, nil
+}
+
+// NotesInfoParams contains the parameters for fetching note info
+type NotesInfoParams struct {
+	Notes []float64 `json:"notes"`
+}
+
+// NoteInfo represents a stored note as returned by notesInfo
+type NoteInfo struct {
+	NoteID    float64                  `json:"noteId"`
+	ModelName string                   `json:"modelName"`
+	Tags      []string                 `json:"tags"`
+	Fields    map[string]NoteInfoField `json:"fields"`
+	Cards     []float64                `json:"cards"`
+}
+
+// OrderedFields returns the note's field values in the order they appear on the note type.
+func (n NoteInfo) OrderedFields() []string {
+	values := make([]string, len(n.Fields))
+	for _, f := range n.Fields {
+		if f.Order >= 0 && f.Order < len(values) {
+			values[f.Order] = f.Value
+		}
+	}
+	return values
+}
+
+// NoteInfoField represents the value and position of a stored note field
+type NoteInfoField struct {
+	Value string `json:"value"`
+	Order int    `json:"order"`
+}
+
+// Info returns the stored fields and tags of the notes with the given ids.
+func (svc *NoteService) Info(ids ...float64) ([]NoteInfo, error) {
+	var infos []NoteInfo
+	if err := svc.client.sendAndUnmarshal("notesInfo", NotesInfoParams{Notes: ids}, &infos); err != nil {
+		return nil, fmt.Errorf("notesInfo: %w", err)
+	}
+	return infos, nil
+}
+
+// UpdateNoteParams contains the parameters for updating a note
+type UpdateNoteParams struct {
+	Note UpdateNote `json:"note"`
+}
+
+// UpdateNote represents the parts of an existing note that can be updated
+type UpdateNote struct {
+	ID      float64                `json:"id"`
+	Fields  map[string]interface{} `json:"fields"`
+	Tags    []string               `json:"tags"`
+	Audio   []NoteMedia            `json:"audio,omitempty"`
+	Video   []NoteMedia            `json:"video,omitempty"`
+	Picture []NoteMedia            `json:"picture,omitempty"`
+}
+
+// Update replaces the fields, tags and media of the note with the given id with those of note.
+func (svc *NoteService) Update(id float64, note Note) error {
+	params := UpdateNoteParams{
+		Note: UpdateNote{
+			ID:      id,
+			Fields:  note.Fields,
+			Tags:    note.Tags,
+			Audio:   note.Audio,
+			Video:   note.Video,
+			Picture: note.Picture,
+		},
+	}
+	if _, err := svc.client.Send("updateNote", params); err != nil {
+		return fmt.Errorf("updateNote: %w", err)
+	}
+	return nil
+}
+
+// DuplicateQuery returns an Anki search query that finds the notes Anki would consider duplicates of n.
+// Like Anki, duplicates are matched on the first field of the model, within the note's duplicate scope.
+func (n Note) DuplicateQuery(firstField string) string {
+	value := fmt.Sprint(n.Fields[firstField])
+	parts := []string{fmt.Sprintf(`"%s:%s"`, EscapeSearchText(firstField), EscapeSearchText(value))}
+
+	if n.Options.DuplicateScope == "deck" {
+		deckName := n.Options.DuplicateScopeOptions.DeckName
+		if deckName == "" {
+			deckName = n.DeckName
+		}
+		parts = append(parts, fmt.Sprintf(`"deck:%s"`, EscapeSearchText(deckName)))
+		if !n.Options.DuplicateScopeOptions.CheckChildren {
+			parts = append(parts, fmt.Sprintf(`-"deck:%s::*"`, EscapeSearchText(deckName)))
+		}
+	}
+	if !n.Options.DuplicateScopeOptions.CheckAllModels {
+		parts = append(parts, fmt.Sprintf(`"note:%s"`, EscapeSearchText(n.ModelName)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// EscapeSearchText escapes the characters that have a special meaning inside a quoted Anki search term.
+func EscapeSearchText(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		`*`, `\*`,
+		`_`, `\_`,
+	).Replace(text)
+}
+
 // NoteParams contains the parameters for adding a new note
 type NoteParams struct {
 	Note Note `json:"note"`
@@ -1,6 +1,9 @@
 package anki_test
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 
@@ -189,3 +192,76 @@ func TestMediaWithNoFields(t *testing.T) {
 		t.Errorf("Expected no fields for audio, got %v", note.Audio[0].Fields)
 	}
 }
+
+func TestNoteService_Delete(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"result": null, "error": null}`))
+	}))
+	defer server.Close()
+
+	client := anki.NewClient(server.URL)
+	if err := client.Notes().Delete(1496198395707, 1496198395708); err != nil {
+		t.Fatalf("Delete() returned an error: %v", err)
+	}
+
+	if payload["action"] != "deleteNotes" {
+		t.Errorf("Expected action to be 'deleteNotes', got '%v'", payload["action"])
+	}
+	params, ok := payload["params"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected params to be an object, got %v", payload["params"])
+	}
+	if notes, ok := params["notes"].([]interface{}); !ok || len(notes) != 2 {
+		t.Errorf("Expected 2 note ids, got %v", params["notes"])
+	}
+}
+
+func TestNote_DuplicateQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		note     anki.Note
+		expected string
+	}{
+		{
+			name:     "deck scope excludes children and other models",
+			note:     anki.NewNoteBuilder("Haki::Go", "Basic", map[string]interface{}{"Front": "What is a goroutine?"}).Build(),
+			expected: `"Front:What is a goroutine?" "deck:Haki::Go" -"deck:Haki::Go::*" "note:Basic"`,
+		},
+		{
+			name:     "collection scope only matches the field",
+			note:     anki.NewNoteBuilder("Haki", "Basic", map[string]interface{}{"Front": "x"}).SetDuplicateScope("collection").Build(),
+			expected: `"Front:x" "note:Basic"`,
+		},
+		{
+			name:     "special characters are escaped",
+			note:     anki.NewNoteBuilder("Deck", "Basic", map[string]interface{}{"Front": `a_b*"c"`}).SetDuplicateScope("collection").Build(),
+			expected: `"Front:a\_b\*\"c\"" "note:Basic"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := tt.note.DuplicateQuery("Front"); actual != tt.expected {
+				t.Errorf("Expected query to be %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNoteInfo_OrderedFields(t *testing.T) {
+	info := anki.NoteInfo{
+		Fields: map[string]anki.NoteInfoField{
+			"Back":  {Value: "Paris", Order: 1},
+			"Front": {Value: "What is the capital of France?", Order: 0},
+		},
+	}
+
+	expected := []string{"What is the capital of France?", "Paris"}
+	if actual := info.OrderedFields(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected fields to be %v, got %v", expected, actual)
+	}
+}
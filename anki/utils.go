@@ -38,3 +38,8 @@ func FilterDecksByHierarchy(deckNames DeckNames) []string {
 
 	return result
 }
+
+// NormalizeTag turns a free form tag into one Anki accepts, since tags are separated by spaces.
+func NormalizeTag(tag string) string {
+	return strings.Join(strings.Fields(tag), "_")
+}
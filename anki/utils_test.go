@@ -60,3 +60,19 @@ func Test_FilterDecksByHierarchy(t *testing.T) {
 		})
 	}
 }
+
+func Test_NormalizeTag(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"geography":        "geography",
+		"  world history ": "world_history",
+		"tcp\tcongestion":  "tcp_congestion",
+		"":                 "",
+	}
+	for tag, expected := range tests {
+		if actual := anki.NormalizeTag(tag); actual != expected {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", tag, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Package chunk splits long documents into pieces small enough to generate cards from in one request.
+//
+// Text is split at Markdown headings first, then between paragraphs, then between sentences, and only
+// between words as a last resort. Fenced code blocks are never split.
+package chunk
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Options controls how text is split.
+type Options struct {
+	// MaxTokens is the most tokens a chunk's body may have.
+	MaxTokens int
+	// Overlap is roughly how many tokens from the end of a chunk are repeated at the start of the next,
+	// so facts spanning a boundary aren't lost.
+	Overlap int
+	// CountTokens estimates the number of tokens in a piece of text.
+	CountTokens func(string) int
+}
+
+// Chunk is a piece of a document.
+type Chunk struct {
+	Index int
+	// Headings are the Markdown headings the chunk starts under, outermost first.
+	Headings []string
+	// Overlap is the end of the previous chunk, repeated for context. Empty for the first chunk.
+	Overlap string
+	// Body is the text the chunk covers.
+	Body string
+}
+
+// Text returns the chunk's overlap followed by its body.
+func (c Chunk) Text() string {
+	if c.Overlap == "" {
+		return c.Body
+	}
+	return c.Overlap + "\n\n" + c.Body
+}
+
+// Heading returns the chunk's heading path, e.g. "TCP > Congestion control".
+func (c Chunk) Heading() string {
+	return strings.Join(c.Headings, " > ")
+}
+
+var (
+	headingRegex  = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	sentenceRegex = regexp.MustCompile(`[^.!?]+(?:[.!?]+["')\]]*\s*|$)`)
+)
+
+// block is a paragraph, code block or heading, the smallest unit chunks are normally built from.
+type block struct {
+	text     string
+	headings []string
+	heading  bool
+}
+
+// Split splits text into chunks. Text that fits in MaxTokens, or any text when MaxTokens isn't positive,
+// is returned as a single chunk.
+func Split(text string, opts Options) []Chunk {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if opts.CountTokens == nil {
+		opts.CountTokens = func(s string) int { return len(strings.Fields(s)) }
+	}
+	if opts.MaxTokens <= 0 || opts.CountTokens(text) <= opts.MaxTokens {
+		return []Chunk{{Body: text, Headings: firstHeadings(text)}}
+	}
+
+	var chunks []Chunk
+	var body []string
+	var headings []string
+	tokens := 0
+	flush := func() {
+		if len(body) == 0 {
+			return
+		}
+		c := Chunk{Index: len(chunks), Headings: headings, Body: strings.Join(body, "\n\n")}
+		if len(chunks) > 0 {
+			c.Overlap = tail(chunks[len(chunks)-1].Body, opts)
+		}
+		chunks = append(chunks, c)
+		body, tokens = nil, 0
+	}
+
+	for _, b := range splitBlocks(text, opts) {
+		n := opts.CountTokens(b.text)
+		// A heading ends the chunk once it's half full, so chunks tend to follow the document's sections.
+		if tokens > 0 && (tokens+n > opts.MaxTokens || (b.heading && tokens >= opts.MaxTokens/2)) {
+			flush()
+		}
+		if len(body) == 0 {
+			headings = b.headings
+		}
+		body = append(body, b.text)
+		tokens += n
+	}
+	flush()
+	return chunks
+}
+
+// splitBlocks splits text into blocks no larger than MaxTokens, except for code blocks.
+func splitBlocks(text string, opts Options) []block {
+	var blocks []block
+	var headings []string
+	var para []string
+	inCode := false
+	flush := func() {
+		if p := strings.TrimSpace(strings.Join(para, "\n")); p != "" {
+			for _, piece := range splitLarge(p, opts) {
+				blocks = append(blocks, block{text: piece, headings: headings})
+			}
+		}
+		para = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			if !inCode {
+				flush()
+			}
+			para = append(para, line)
+			if inCode {
+				blocks = append(blocks, block{text: strings.Join(para, "\n"), headings: headings})
+				para = nil
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			para = append(para, line)
+			continue
+		}
+
+		if m := headingRegex.FindStringSubmatch(trimmed); m != nil {
+			flush()
+			level := len(m[1])
+			headings = append(headings[:min(level-1, len(headings)):min(level-1, len(headings))], m[2])
+			blocks = append(blocks, block{text: trimmed, headings: headings, heading: true})
+			continue
+		}
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		para = append(para, line)
+	}
+	flush()
+	return blocks
+}
+
+// splitLarge splits a paragraph that's too large for a chunk between sentences, and between words when
+// a single sentence is too large.
+func splitLarge(p string, opts Options) []string {
+	if opts.CountTokens(p) <= opts.MaxTokens {
+		return []string{p}
+	}
+
+	var pieces []string
+	var cur strings.Builder
+	add := func(s string) {
+		if cur.Len() > 0 && opts.CountTokens(cur.String()+s) > opts.MaxTokens {
+			pieces = append(pieces, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		}
+		cur.WriteString(s)
+	}
+	for _, sentence := range sentenceRegex.FindAllString(p, -1) {
+		if opts.CountTokens(sentence) <= opts.MaxTokens {
+			add(sentence)
+			continue
+		}
+		for _, w := range strings.Fields(sentence) {
+			add(w + " ")
+		}
+	}
+	if strings.TrimSpace(cur.String()) != "" {
+		pieces = append(pieces, strings.TrimSpace(cur.String()))
+	}
+	return pieces
+}
+
+// tail returns the last sentences of text, up to roughly Overlap tokens.
+func tail(text string, opts Options) string {
+	if opts.Overlap <= 0 {
+		return ""
+	}
+	sentences := sentenceRegex.FindAllString(text, -1)
+	start := len(sentences)
+	tokens := 0
+	for start > 0 {
+		n := opts.CountTokens(sentences[start-1])
+		if tokens+n > opts.Overlap {
+			break
+		}
+		tokens += n
+		start--
+	}
+	return strings.TrimSpace(strings.Join(sentences[start:], ""))
+}
+
+// firstHeadings returns the heading path the text starts under.
+func firstHeadings(text string) []string {
+	for _, line := range strings.Split(text, "\n") {
+		if m := headingRegex.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			return []string{m[2]}
+		}
+		if strings.TrimSpace(line) != "" {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package chunk_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/netr/haki/chunk"
+)
+
+func words(s string) int {
+	return len(strings.Fields(s))
+}
+
+func TestSplit_ShortTextIsOneChunk(t *testing.T) {
+	chunks := chunk.Split("# TCP\n\nTCP is reliable.", chunk.Options{MaxTokens: 100, CountTokens: words})
+	if len(chunks) != 1 {
+		t.Fatalf("Expected 1 chunk, got %d", len(chunks))
+	}
+	if chunks[0].Heading() != "TCP" {
+		t.Errorf("Expected heading 'TCP', got '%s'", chunks[0].Heading())
+	}
+	if chunks[0].Overlap != "" {
+		t.Errorf("Expected no overlap, got '%s'", chunks[0].Overlap)
+	}
+}
+
+func TestSplit_FollowsHeadings(t *testing.T) {
+	text := `# Networking
+
+## TCP
+
+TCP is a reliable transport protocol. It retransmits lost segments.
+
+## UDP
+
+UDP is an unreliable transport protocol. It has no retransmissions.`
+
+	chunks := chunk.Split(text, chunk.Options{MaxTokens: 16, Overlap: 6, CountTokens: words})
+	if len(chunks) != 2 {
+		t.Fatalf("Expected 2 chunks, got %d: %+v", len(chunks), chunks)
+	}
+	if got := chunks[0].Heading(); got != "Networking" {
+		t.Errorf("Expected the first chunk under 'Networking', got '%s'", got)
+	}
+	if got := chunks[1].Heading(); got != "Networking > UDP" {
+		t.Errorf("Expected the second chunk under 'Networking > UDP', got '%s'", got)
+	}
+	if !strings.HasPrefix(chunks[1].Body, "## UDP") {
+		t.Errorf("Expected the second chunk to start at its heading, got '%s'", chunks[1].Body)
+	}
+	if chunks[1].Overlap != "It retransmits lost segments." {
+		t.Errorf("Expected the last sentence of the first chunk as overlap, got '%s'", chunks[1].Overlap)
+	}
+	if !strings.HasPrefix(chunks[1].Text(), chunks[1].Overlap) {
+		t.Error("Expected Text() to start with the overlap")
+	}
+}
+
+func TestSplit_LargeParagraphsSplitBetweenSentences(t *testing.T) {
+	text := "One two three four. Five six seven eight. Nine ten eleven twelve."
+
+	chunks := chunk.Split(text, chunk.Options{MaxTokens: 8, CountTokens: words})
+	if len(chunks) != 2 {
+		t.Fatalf("Expected 2 chunks, got %d: %+v", len(chunks), chunks)
+	}
+	if chunks[0].Body != "One two three four. Five six seven eight." {
+		t.Errorf("Unexpected first chunk '%s'", chunks[0].Body)
+	}
+	for _, c := range chunks {
+		if words(c.Body) > 8 {
+			t.Errorf("Expected at most 8 tokens, got %d", words(c.Body))
+		}
+	}
+}
+
+func TestSplit_KeepsCodeBlocksIntact(t *testing.T) {
+	text := "Intro text here.\n\n```go\nfunc main() {\n\n\tfmt.Println(\"hi\")\n}\n```\n\nOutro text here."
+
+	chunks := chunk.Split(text, chunk.Options{MaxTokens: 5, CountTokens: words})
+	var found bool
+	for _, c := range chunks {
+		if strings.Contains(c.Body, "```go") {
+			found = true
+			if !strings.Contains(c.Body, "fmt.Println(\"hi\")\n}\n```") {
+				t.Errorf("Expected the code block in one piece, got '%s'", c.Body)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("Expected a chunk with the code block")
+	}
+}
@@ -15,7 +15,7 @@ func NewCardTestCommand(apiKey string) *cli.Command {
 	return &cli.Command{
 		Name:      "cardtest",
 		Usage:     "Test creating a card for the specified word.",
-		ArgsUsage: "--word <word>",
+		ArgsUsage: "--words <word>",
 		Flags:     []cli.Flag{newWordsFlag()},
 		Action:    actionCardTest(apiKey),
 		Aliases:   []string{"test"},
@@ -24,7 +24,7 @@ func NewCardTestCommand(apiKey string) *cli.Command {
 
 func actionCardTest(apiKey string) func(cCtx *cli.Context) error {
 	return func(cCtx *cli.Context) error {
-		word := cCtx.String("word")
+		word := cCtx.String("words")
 		if word == "" {
 			return ErrWordFlagRequired
 		}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/netr/haki/ai"
+	"github.com/netr/haki/chunk"
+	"github.com/netr/haki/prompt"
+)
+
+// duplicateCardSimilarity is how alike two fronts from different chunks must be to keep only one of them.
+const duplicateCardSimilarity = 0.8
+
+// GenerationTimeout is how long generating the cards for query may take: a request's worth for every chunk
+// the query is split into.
+func (t *BasePlugin) GenerationTimeout(query string) time.Duration {
+	return time.Duration(max(1, len(t.chunkInput(t.generationQuery(query))))) * aiTimeout
+}
+
+// chunkInput splits long input into chunks that each fit in a single request.
+func (t *BasePlugin) chunkInput(input string) []chunk.Chunk {
+	return chunk.Split(input, chunk.Options{
+		MaxTokens:   t.config.ChunkTokens,
+		Overlap:     t.config.ChunkOverlap,
+		CountTokens: prompt.EstimateTokens,
+	})
+}
+
+// generateChunkedCards generates cards for each chunk in turn and removes the cards repeated across chunks.
+// A count is spread over the chunks that are left, so chunks make up for the duplicates dropped before them.
+// Every chunk gets its own timeout within ctx, see GenerationTimeout.
+func (t *BasePlugin) generateChunkedCards(ctx context.Context, chunks []chunk.Chunk, count int) ([]ai.AnkiCard, error) {
+	var cards []ai.AnkiCard
+	for _, c := range chunks {
+		share := 0
+		if count > 0 {
+			left := len(chunks) - c.Index
+			if share = (count - len(cards) + left - 1) / left; share <= 0 {
+				break
+			}
+		}
+
+		chunkCtx, cancel := context.WithTimeout(ctx, aiTimeout)
+		generated, err := t.generateCards(chunkCtx, chunkQuery(c), share)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d/%d: %w", c.Index+1, len(chunks), err)
+		}
+
+		before := len(cards)
+		cards = dedupeCards(cards, generated)
+		slog.Info("chunk generated",
+			slog.Int("chunk", c.Index+1),
+			slog.Int("chunks", len(chunks)),
+			slog.String("heading", c.Heading()),
+			slog.Int("cards", len(generated)),
+			slog.Int("duplicates", len(generated)-(len(cards)-before)),
+		)
+	}
+	if count > 0 && len(cards) < count {
+		slog.Warn("fewer cards than requested", slog.Int("count", len(cards)), slog.Int("requested", count))
+	}
+	return cards, nil
+}
+
+// chunkQuery tells the model which part of the chunk is only there for context.
+func chunkQuery(c chunk.Chunk) string {
+	text := c.Body
+	if c.Heading() != "" {
+		text = fmt.Sprintf("Section: %s\n\n%s", c.Heading(), text)
+	}
+	if c.Overlap == "" {
+		return text
+	}
+	return fmt.Sprintf("Context from the previous section, only for reference:\n%s\n\n%s", c.Overlap, text)
+}
+
+// dedupeCards appends the new cards that aren't near-duplicates of a card already in cards.
+func dedupeCards(cards, generated []ai.AnkiCard) []ai.AnkiCard {
+	for _, g := range generated {
+		duplicate := false
+		for _, c := range cards {
+			if normalizeQuery(c.Front) == normalizeQuery(g.Front) || querySimilarity(c.Front, g.Front) >= duplicateCardSimilarity {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			cards = append(cards, g)
+		}
+	}
+	return cards
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"github.com/netr/haki/ai"
+	"github.com/netr/haki/anki"
+	"github.com/netr/haki/prompt"
+)
+
+// CommandConfig is the per command section of the config file.
+type CommandConfig struct {
+	// NoteType is the Anki note type basic cards are stored as. Cloze cards always use Anki's Cloze type.
+	NoteType string `json:"note_type"`
+	// Fields maps card attributes onto the fields of NoteType.
+	Fields FieldMapping `json:"fields"`
+	// Prompt is the name of the prompt template used to generate cards.
+	Prompt string `json:"prompt"`
+	// DeckPrompts overrides Prompt for specific decks, keyed by deck name.
+	DeckPrompts map[string]string `json:"deck_prompts,omitempty"`
+	// Language is the language cards are written in.
+	Language string `json:"language"`
+	// ExampleTokens is the prompt token budget for example cards taken from the chosen deck. -1 disables examples.
+	ExampleTokens int `json:"example_tokens"`
+	// ChunkTokens is the largest input, in tokens, sent in one request. Longer input is split into chunks
+	// that overlap by ChunkOverlap tokens. -1 disables chunking.
+	ChunkTokens  int `json:"chunk_tokens,omitempty"`
+	ChunkOverlap int `json:"chunk_overlap,omitempty"`
+	// Model is the AI model used to generate cards. Empty uses the default model.
+	Model string `json:"model,omitempty"`
+	// MaxCards caps the number of cards stored per run. Zero means no limit.
+	MaxCards int `json:"max_cards,omitempty"`
+	// Level is the audience cards are written for: beginner, intermediate or expert. Empty lets the model decide.
+	Level string `json:"level,omitempty"`
+	// Style is atomic for one fact per card or comprehensive for in-depth cards. Empty lets the model decide.
+	Style string `json:"style,omitempty"`
+	// TTS and Image turn the media generation steps on or off. Both default to on.
+	TTS   *bool `json:"tts,omitempty"`
+	Image *bool `json:"image,omitempty"`
+	// Tags are added to every note.
+	Tags []string `json:"tags,omitempty"`
+	// DeckRoots limit the decks the AI chooses from. A root is a deck name, which includes its subdecks,
+	// or a glob pattern matched against full deck names.
+	DeckRoots []string `json:"deck_roots,omitempty"`
+	// Routes send matching queries straight to a deck, before the AI is asked.
+	Routes []RouteRule `json:"routes,omitempty"`
+	// DeckConfidence is the confidence below which the user picks from the AI's deck suggestions.
+	DeckConfidence float64 `json:"deck_confidence,omitempty"`
+	// Review is auto, always or never. Auto reviews cards before storing them when haki runs in a terminal.
+	Review string `json:"review,omitempty"`
+	// Critic is off, auto or review. Auto applies the critic's rewrites, review shows them while reviewing.
+	Critic string `json:"critic,omitempty"`
+	// Grounded makes every card cite a verbatim quote from the input. Cards whose quote can't be found are
+	// tagged, or dropped when UnsupportedCards is "drop".
+	Grounded         bool   `json:"grounded,omitempty"`
+	UnsupportedCards string `json:"unsupported_cards,omitempty"`
+	// FallbackDeck is used instead of an unsure suggestion when nobody is there to pick. Empty uses the top suggestion.
+	FallbackDeck string `json:"fallback_deck,omitempty"`
+	// Columns maps highlight attributes onto the columns of an imported CSV, like "highlight=Quote,title=Book".
+	Columns string `json:"columns,omitempty"`
+}
+
+// defaultModel is used when neither the command line nor the config name a model.
+const defaultModel = string(ai.GPT4o20241120)
+
+// defaultExampleTokens leaves plenty of room for the prompt and the generated cards.
+const defaultExampleTokens = 1500
+
+// defaultChunkTokens keeps each chunk's cards well within the response token limit.
+const (
+	defaultChunkTokens  = 2000
+	defaultChunkOverlap = 150
+)
+
+// defaultDeckConfidence lets clear-cut deck suggestions through and asks about the rest.
+const defaultDeckConfidence = 0.6
+
+// DefaultCommandConfigs returns the configs used for commands that are missing from the config file.
+func DefaultCommandConfigs() map[string]*CommandConfig {
+	return map[string]*CommandConfig{
+		"topic":    topicCommandConfig(),
+		"file":     topicCommandConfig(),
+		"obsidian": topicCommandConfig(),
+		"epub":     topicCommandConfig(),
+		"readwise": topicCommandConfig(),
+		"csv":      topicCommandConfig(),
+		"vocab": {
+			NoteType:       vocabModelName,
+			DeckRoots:      []string{"Vocabulary"},
+			Fields:         vocabFieldMapping,
+			Prompt:         prompt.DefaultName,
+			Language:       "English",
+			ExampleTokens:  defaultExampleTokens,
+			DeckConfidence: defaultDeckConfidence,
+			ChunkTokens:    defaultChunkTokens,
+			ChunkOverlap:   defaultChunkOverlap,
+		},
+		"subs": {
+			NoteType:       anki.ModelBasic,
+			DeckRoots:      []string{"Vocabulary"},
+			Fields:         basicFieldMapping,
+			Prompt:         prompt.DefaultName,
+			Language:       "English",
+			ExampleTokens:  defaultExampleTokens,
+			DeckConfidence: defaultDeckConfidence,
+			ChunkTokens:    defaultChunkTokens,
+			ChunkOverlap:   defaultChunkOverlap,
+		},
+	}
+}
+
+// topicCommandConfig is the default config of the commands that run their input through the topic pipeline.
+func topicCommandConfig() *CommandConfig {
+	return &CommandConfig{
+		NoteType:       anki.ModelBasic,
+		DeckRoots:      []string{"Haki"},
+		Fields:         basicFieldMapping,
+		Prompt:         prompt.DefaultName,
+		Language:       "English",
+		ExampleTokens:  defaultExampleTokens,
+		DeckConfidence: defaultDeckConfidence,
+		ChunkTokens:    defaultChunkTokens,
+		ChunkOverlap:   defaultChunkOverlap,
+	}
+}
+
+// WithDefaults fills in anything left empty in c from the default config for the named command.
+func (c CommandConfig) WithDefaults(command string) CommandConfig {
+	def, ok := DefaultCommandConfigs()[command]
+	if !ok {
+		return c
+	}
+	if c.NoteType == "" {
+		c.NoteType = def.NoteType
+		c.Fields = def.Fields
+	}
+	if c.Fields.Front == "" {
+		c.Fields.Front = def.Fields.Front
+	}
+	if c.Fields.Back == "" {
+		c.Fields.Back = def.Fields.Back
+	}
+	if c.Prompt == "" {
+		c.Prompt = def.Prompt
+	}
+	if c.Language == "" {
+		c.Language = def.Language
+	}
+	if c.ExampleTokens == 0 {
+		c.ExampleTokens = def.ExampleTokens
+	}
+	if c.DeckRoots == nil {
+		c.DeckRoots = def.DeckRoots
+	}
+	if c.ChunkTokens == 0 {
+		c.ChunkTokens = def.ChunkTokens
+	}
+	if c.ChunkOverlap == 0 {
+		c.ChunkOverlap = def.ChunkOverlap
+	}
+	if c.DeckConfidence == 0 {
+		c.DeckConfidence = def.DeckConfidence
+	}
+	return c
+}
+
+// ttsEnabled reports whether text-to-speech audio should be generated.
+func (c CommandConfig) ttsEnabled() bool {
+	return c.TTS == nil || *c.TTS
+}
+
+// imageEnabled reports whether an image should be generated.
+func (c CommandConfig) imageEnabled() bool {
+	return c.Image == nil || *c.Image
+}
+
+// model returns the model to generate cards with, with modelOverride taking precedence over the config.
+func (c CommandConfig) model(modelOverride string) string {
+	if modelOverride != "" {
+		return modelOverride
+	}
+	if c.Model != "" {
+		return c.Model
+	}
+	return defaultModel
+}
+
+// promptSelector returns the prompt selector for the command, with promptOverride taking precedence over the config.
+func (c CommandConfig) promptSelector(promptOverride string) PromptSelector {
+	return PromptSelector{
+		Override: promptOverride,
+		Decks:    c.DeckPrompts,
+		Default:  c.Prompt,
+	}
+}
+
+// PromptSelector picks the prompt template for a deck: the --prompt-name flag wins,
+// then the prompt configured for the deck, then the command's prompt.
+type PromptSelector struct {
+	Override string
+	Decks    map[string]string
+	Default  string
+}
+
+// Name returns the name of the prompt template to use for the deck.
+func (p PromptSelector) Name(deckName string) string {
+	if p.Override != "" {
+		return p.Override
+	}
+	if name, ok := p.Decks[deckName]; ok && name != "" {
+		return name
+	}
+	if p.Default != "" {
+		return p.Default
+	}
+	return prompt.DefaultName
+}
+
+// noteType returns the note type and field mapping to use, with noteTypeOverride taking precedence over the config.
+// Overriding with one of Anki's built-in note types uses that type's standard fields.
+func (c CommandConfig) noteType(noteTypeOverride string) (string, FieldMapping) {
+	if noteTypeOverride == "" || noteTypeOverride == c.NoteType {
+		return c.NoteType, c.Fields
+	}
+	switch noteTypeOverride {
+	case anki.ModelBasic, anki.ModelBasicReversed:
+		return noteTypeOverride, basicFieldMapping
+	default:
+		return noteTypeOverride, c.Fields
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/netr/haki/ai"
+)
+
+var ErrInvalidCriticMode = errors.New("invalid critic mode, expected off, auto or review")
+
+// CriticMode decides whether generated cards get a second pass against spaced repetition best practices.
+type CriticMode string
+
+const (
+	// CriticOff skips the critic, which is the default.
+	CriticOff CriticMode = "off"
+	// CriticAuto applies the critic's rewrites and splits before the cards are reviewed or stored.
+	CriticAuto CriticMode = "auto"
+	// CriticReview shows the critic's scores and suggestions in review mode, where they can be applied per card.
+	CriticReview CriticMode = "review"
+)
+
+func ParseCriticMode(s string) (CriticMode, error) {
+	switch m := CriticMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case CriticOff, CriticAuto, CriticReview:
+		return m, nil
+	case "":
+		return CriticOff, nil
+	default:
+		return "", fmt.Errorf("%s: %w", s, ErrInvalidCriticMode)
+	}
+}
+
+// criticMode returns the critic mode, with the flag taking precedence over the config.
+func (t *BasePlugin) criticMode() (CriticMode, error) {
+	if t.overrides.Critic != "" {
+		return t.overrides.Critic, nil
+	}
+	mode, err := ParseCriticMode(t.config.Critic)
+	if err != nil {
+		return "", fmt.Errorf("config: %w", err)
+	}
+	return mode, nil
+}
+
+// critiqueCards runs the critic over the cards. Under auto the suggestions are applied right away,
+// under review they're kept for ReviewAnkiCards. A failing critic only costs its suggestions, so it is logged.
+func (t *BasePlugin) critiqueCards(ctx context.Context, cards []ai.AnkiCard) ([]ai.AnkiCard, error) {
+	mode, err := t.criticMode()
+	if err != nil || mode == CriticOff || len(cards) == 0 {
+		return cards, err
+	}
+	critic, ok := t.ankiAI.(ai.Critic)
+	if !ok {
+		slog.Warn("critic not supported", slog.String("model", t.ankiAI.ModelName().String()))
+		return cards, nil
+	}
+
+	critiques, err := critic.CritiqueAnkiCards(ctx, cards)
+	if err != nil {
+		slog.Error("critique cards", slog.String("error", err.Error()))
+		return cards, nil
+	}
+	for _, c := range critiques {
+		slog.Info("card critiqued",
+			slog.String("front", cards[c.Index].Front),
+			slog.Float64("score", c.Scores.Average()),
+			slog.Int("rewrites", len(c.Rewrites)),
+		)
+	}
+
+	if mode == CriticAuto {
+		return ai.ApplyCritiques(cards, critiques), nil
+	}
+	t.critiques = make(map[int]ai.Critique, len(critiques))
+	for _, c := range critiques {
+		t.critiques[c.Index] = c
+	}
+	return cards, nil
+}
+
+// keepCritiques moves the critiques along with their cards after some were dropped. kept holds the position
+// each remaining card had when it was critiqued.
+func (t *BasePlugin) keepCritiques(kept []int) {
+	if t.critiques == nil {
+		return
+	}
+	critiques := make(map[int]ai.Critique, len(kept))
+	for i, old := range kept {
+		if c, ok := t.critiques[old]; ok {
+			critiques[i] = c
+		}
+	}
+	t.critiques = critiques
+}
+
+// printCritique shows the critic's verdict on a card under review.
+func printCritique(c ai.Critique) {
+	s := c.Scores
+	fmt.Printf("%sCritic:%s %.0f%% (minimum information %.1f, unambiguous %.1f, no enumeration %.1f, back length %.1f)\n",
+		colors.Cyan, colors.Reset, s.Average()*100, s.MinimumInformation, s.Unambiguous, s.NoEnumeration, s.BackLength)
+	for _, issue := range c.Issues {
+		fmt.Printf("  - %s\n", issue)
+	}
+	for i, r := range c.Rewrites {
+		fmt.Printf("%sSuggestion %d/%d:%s\n%s", colors.Cyan, i+1, len(c.Rewrites), colors.Reset, colors.BeautifyCard(r))
+	}
+}
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/netr/haki/anki"
+)
+
+var (
+	ErrInvalidDuplicatePolicy = errors.New("invalid duplicate policy, expected skip, update, fail or allow")
+	ErrModelHasNoFields       = errors.New("model has no fields")
+)
+
+// DuplicatePolicy decides what happens when a generated note already exists in its deck.
+type DuplicatePolicy string
+
+const (
+	// DuplicateSkip leaves the existing note alone and doesn't add the new one.
+	DuplicateSkip DuplicatePolicy = "skip"
+	// DuplicateUpdate overwrites the existing note's fields, tags and media with the new ones.
+	DuplicateUpdate DuplicatePolicy = "update"
+	// DuplicateFail lets AnkiConnect reject the note, which is the default.
+	DuplicateFail DuplicatePolicy = "fail"
+	// DuplicateAllow adds the note next to the existing one.
+	DuplicateAllow DuplicatePolicy = "allow"
+)
+
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	switch p := DuplicatePolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case DuplicateSkip, DuplicateUpdate, DuplicateFail, DuplicateAllow:
+		return p, nil
+	case "":
+		return DuplicateFail, nil
+	default:
+		return "", fmt.Errorf("%s: %w", s, ErrInvalidDuplicatePolicy)
+	}
+}
+
+// storeNote adds the note to Anki according to the plugin's duplicate policy and records it in the run journal.
+// media is the list of media filenames the note stores in Anki.
+func (t *BasePlugin) storeNote(note anki.Note, media ...string) error {
+	if tag := t.promptTag(); tag != "" {
+		note.Tags = append(note.Tags, tag)
+	}
+	for _, tag := range t.config.Tags {
+		if tag = anki.NormalizeTag(tag); tag != "" && !slices.Contains(note.Tags, tag) {
+			note.Tags = append(note.Tags, tag)
+		}
+	}
+
+	// Notes made from an earlier version of the input are overwritten in place, so they keep their review history.
+	if replaced, err := t.replaceNote(note); err != nil || replaced {
+		return err
+	}
+
+	switch t.onDuplicate {
+	case DuplicateAllow:
+		note.Options.AllowDuplicate = true
+	case DuplicateSkip, DuplicateUpdate:
+		existing, err := t.findDuplicate(note)
+		if err != nil {
+			return fmt.Errorf("store note: %w", err)
+		}
+		if existing != nil {
+			// The existing note stands in for this card, but haki didn't create it, so it is kept apart from the
+			// stored notes: neither undo nor a synced section may ever replace or delete it.
+			if t.onDuplicate == DuplicateSkip {
+				fmt.Printf("Skipped duplicate of note %.f\n", existing.NoteID)
+				t.matched = append(t.matched, existing.NoteID)
+				return nil
+			}
+			if err := t.updateNote(*existing, note); err != nil {
+				return err
+			}
+			t.matched = append(t.matched, existing.NoteID)
+			return nil
+		}
+	}
+
+	id, err := t.ankiClient.Notes().Add(note)
+	if err != nil {
+		return fmt.Errorf("store note: %w", err)
+	}
+	t.run.AddNote(id, media...)
+	t.noteIDs = append(t.noteIDs, id)
+	slog.Info(
+		"note added",
+		slog.String("deck", note.DeckName),
+		slog.String("model", note.ModelName),
+		slog.String("id", fmt.Sprintf("%.f", id)),
+	)
+	return nil
+}
+
+// replaceNote overwrites the next of the plugin's replaceable notes with note. It reports false when there is
+// nothing left to replace; notes deleted in Anki are passed over, and notes of a different type are left unused
+// since Anki can't change a note's type.
+func (t *BasePlugin) replaceNote(note anki.Note) (bool, error) {
+	for len(t.replace) > 0 {
+		id := t.replace[0]
+		t.replace = t.replace[1:]
+
+		infos, err := t.ankiClient.Notes().Info(id)
+		if err != nil {
+			return false, fmt.Errorf("replace note: %w", err)
+		}
+		if len(infos) == 0 || infos[0].NoteID == 0 {
+			continue
+		}
+		if infos[0].ModelName != note.ModelName {
+			t.unused = append(t.unused, id)
+			continue
+		}
+		if err := t.updateNote(infos[0], note); err != nil {
+			return false, fmt.Errorf("replace note: %w", err)
+		}
+		t.noteIDs = append(t.noteIDs, id)
+		return true, nil
+	}
+	return false, nil
+}
+
+// storedNotes returns the ids of the notes the plugin added or replaced, and of the replaceable notes it didn't need.
+// Duplicates of notes that were already in Anki are in neither.
+func (t *BasePlugin) storedNotes() (stored, unused []float64) {
+	unused = append(slices.Clone(t.unused), t.replace...)
+	return t.noteIDs, slices.DeleteFunc(unused, func(id float64) bool { return slices.Contains(t.matched, id) })
+}
+
+// hasNotes reports whether any of the plugin's cards are in Anki, stored by it or already there as a duplicate.
+func (t *BasePlugin) hasNotes() bool {
+	return len(t.noteIDs) > 0 || len(t.matched) > 0
+}
+
+// findDuplicate returns the first note Anki would reject the given note as a duplicate of, or nil if there is none.
+func (t *BasePlugin) findDuplicate(note anki.Note) (*anki.NoteInfo, error) {
+	fields, err := t.ankiClient.ModelNames().FieldNames(note.ModelName)
+	if err != nil {
+		return nil, fmt.Errorf("find duplicate: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("find duplicate (%s): %w", note.ModelName, ErrModelHasNoFields)
+	}
+
+	ids, err := t.ankiClient.Notes().Find(note.DuplicateQuery(fields[0]))
+	if err != nil {
+		return nil, fmt.Errorf("find duplicate: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	infos, err := t.ankiClient.Notes().Info(ids[0])
+	if err != nil {
+		return nil, fmt.Errorf("find duplicate: %w", err)
+	}
+	if len(infos) == 0 {
+		return nil, nil
+	}
+	return &infos[0], nil
+}
+
+// updateNote overwrites an existing note with the fields and media of note. Existing tags are kept.
+func (t *BasePlugin) updateNote(existing anki.NoteInfo, note anki.Note) error {
+	changes := noteChanges(existing, note)
+
+	tags := slices.Clone(existing.Tags)
+	for _, tag := range note.Tags {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	note.Tags = tags
+
+	if err := t.ankiClient.Notes().Update(existing.NoteID, note); err != nil {
+		return fmt.Errorf("update note: %w", err)
+	}
+
+	if len(changes) == 0 {
+		fmt.Printf("Updated note %.f: no changes\n", existing.NoteID)
+	} else {
+		fmt.Printf("Updated note %.f: %s\n", existing.NoteID, strings.Join(changes, ", "))
+	}
+	slog.Info(
+		"note updated",
+		slog.String("deck", note.DeckName),
+		slog.String("model", note.ModelName),
+		slog.String("id", fmt.Sprintf("%.f", existing.NoteID)),
+		slog.Int("changes", len(changes)),
+	)
+	return nil
+}
+
+// noteChanges describes how note differs from the stored note: changed fields, new tags and replaced media.
+func noteChanges(existing anki.NoteInfo, note anki.Note) []string {
+	var changes []string
+
+	fieldNames := make([]string, 0, len(note.Fields))
+	for name := range note.Fields {
+		fieldNames = append(fieldNames, name)
+	}
+	slices.Sort(fieldNames)
+	for _, name := range fieldNames {
+		if fmt.Sprint(note.Fields[name]) != existing.Fields[name].Value {
+			changes = append(changes, name)
+		}
+	}
+
+	for _, tag := range note.Tags {
+		if !slices.Contains(existing.Tags, tag) {
+			changes = append(changes, "+tag:"+tag)
+		}
+	}
+
+	for _, media := range [][]anki.NoteMedia{note.Audio, note.Video, note.Picture} {
+		for _, m := range media {
+			if !referencesMedia(existing, m.Filename) {
+				changes = append(changes, "media:"+m.Filename)
+			}
+		}
+	}
+	return changes
+}
+
+// referencesMedia reports whether any field of the stored note refers to the media file.
+func referencesMedia(existing anki.NoteInfo, fileName string) bool {
+	for _, f := range existing.Fields {
+		if strings.Contains(f.Value, fileName) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/netr/haki/anki"
+	"github.com/netr/haki/document"
+	"github.com/netr/haki/epub"
+)
+
+var ErrEPUBRequired = errors.New("epub file is required: haki import epub <file>")
+
+func newEPUBImportCommand(apiKey, outputDir string, config func(command string) CommandConfig, profiles []DeckProfile) *cli.Command {
+	return &cli.Command{
+		Name:      "epub",
+		Usage:     "Generate Anki cards for the chapters of an EPUB book, in a sub-deck per chapter.",
+		ArgsUsage: "--chapters <1,3-5> " + generationArgsUsage + " <file>",
+		Flags:     append([]cli.Flag{newChaptersFlag()}, generationFlags()...),
+		Action: actionFn(
+			NewEPUBImportAction(
+				apiKey,
+				"epub",
+				outputDir,
+				config("epub"),
+				profiles,
+				append([]string{"chapters"}, generationFlagNames...),
+			)),
+	}
+}
+
+func newChaptersFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "chapters",
+		Value: "",
+		Usage: "chapters to import, like 1,3-5 or all. Without it, chapters are picked interactively",
+	}
+}
+
+type EPUBImportAction struct {
+	Action
+	outputDir string
+	config    CommandConfig
+	profiles  []DeckProfile
+}
+
+func NewEPUBImportAction(apiKey, name, outputDir string, cfg CommandConfig, profiles []DeckProfile, flags []string) *EPUBImportAction {
+	return &EPUBImportAction{
+		Action: Action{
+			flags:  flags,
+			apiKey: apiKey,
+			name:   name,
+		},
+		outputDir: outputDir,
+		config:    cfg.WithDefaults(name),
+		profiles:  profiles,
+	}
+}
+
+// TakesArgs makes the file given after the flags part of Run's arguments.
+func (a EPUBImportAction) TakesArgs() bool {
+	return true
+}
+
+func (a EPUBImportAction) Run(args ...interface{}) error {
+	if len(args) < 2+len(generationFlagNames) {
+		return fmt.Errorf("action run: %w", ErrEPUBRequired)
+	}
+	selection := args[0].(string)
+	gen, err := parseGenerationArgs(args[1 : 1+len(generationFlagNames)])
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+	path := args[1+len(generationFlagNames)].(string)
+
+	book, err := epub.Open(path)
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+	imports := NewImportLog(a.outputDir)
+	imported := make([]bool, len(book.Chapters))
+	for i, c := range book.Chapters {
+		if imported[i], err = imports.Imported(a.Name(), chapterKey(book, c)); err != nil {
+			return fmt.Errorf("action run: %w", err)
+		}
+	}
+
+	if selection == "" && isInteractive() {
+		if selection, err = pickChapters(book, imported); err != nil {
+			return fmt.Errorf("action run: %w", err)
+		}
+		if selection == "" {
+			return nil
+		}
+	}
+	chapters, err := epub.SelectChapters(selection, len(book.Chapters))
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+
+	run := NewJournalEntry(a.Name(), path)
+	defer recordRun(a.outputDir, run, gen.Debug)
+
+	model := a.config.model(gen.Overrides.Model)
+	cardCreator, err := newCardCreatorFunc(a.apiKey)(model)
+	if err != nil {
+		return fmt.Errorf("new openai card creator (%s): %w", model, err)
+	}
+	run.Model = cardCreator.ModelName().String()
+
+	opts := newPluginOptions(a.apiKey, a.outputDir, a.Name(), a.config, a.profiles, gen, run)
+	if !gen.Debug {
+		if err := newTopicPlugin(cardCreator, opts).Validate(); err != nil {
+			return fmt.Errorf("action run: %w", err)
+		}
+	}
+
+	// Every chapter is recorded as soon as its cards are stored, so an interrupted import resumes at the next chapter.
+	for _, i := range chapters {
+		chapter := book.Chapters[i]
+		if imported[i] {
+			fmt.Printf("Skipping chapter %d, %s: already imported\n", i+1, chapter.Title)
+			continue
+		}
+
+		deckName := subDeck(strings.ReplaceAll(book.Title, "::", ":"), []string{chapter.Title})
+		if gen.Overrides.Deck != "" {
+			deckName = gen.Overrides.Deck + "::" + deckName
+		}
+		chapterOpts := opts
+		chapterOpts.Origin = book.Title + " › " + chapter.Title
+		chapterOpts.Config.Tags = append(slices.Clone(opts.Config.Tags), anki.NormalizeTag("source::"+book.Title+"::"+chapter.Title))
+
+		slog.Info("importing chapter", slog.Int("chapter", i+1), slog.String("title", chapter.Title), slog.Int("words", chapter.Words()))
+		section := document.Section{Headings: []string{chapter.Title}, Body: chapter.Text}
+		stored, _, err := runSection(cardCreator, deckName, section, gen.Debug, chapterOpts)
+		if err != nil {
+			return fmt.Errorf("import epub (%s): %w", chapter.Title, err)
+		}
+		if gen.Debug {
+			continue
+		}
+		// A chapter whose cards were all rejected can be imported again.
+		if len(stored) == 0 {
+			fmt.Printf("Nothing stored from chapter %d, %s: not marked as imported\n", i+1, chapter.Title)
+			continue
+		}
+		if err := imports.Add(a.Name(), chapterKey(book, chapter)); err != nil {
+			return fmt.Errorf("import epub (%s): %w", chapter.Title, err)
+		}
+	}
+	return nil
+}
+
+// chapterKey identifies a chapter in the import log.
+func chapterKey(book *epub.Book, chapter epub.Chapter) string {
+	return book.ID + "#" + chapter.Href
+}
+
+// pickChapters lists the book's chapters and asks the user which to import. Entering nothing picks every chapter
+// that hasn't been imported yet, which is none when the whole book has been.
+func pickChapters(book *epub.Book, imported []bool) (string, error) {
+	fmt.Printf("%s%s%s", colors.Cyan, book.Title, colors.Reset)
+	if book.Author != "" {
+		fmt.Printf(" by %s", book.Author)
+	}
+	fmt.Println()
+
+	var pending []string
+	for i, c := range book.Chapters {
+		status := ""
+		if imported[i] {
+			status = fmt.Sprintf(" %s(imported)%s", colors.Green, colors.Reset)
+		} else {
+			pending = append(pending, fmt.Sprint(i+1))
+		}
+		fmt.Printf("  %d. %s (%d words)%s\n", i+1, c.Title, c.Words(), status)
+	}
+
+	answer, err := readLine("Chapters to import, like 1,3-5 (enter for every new chapter): ")
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		if len(pending) == 0 {
+			fmt.Println("Every chapter has been imported already.")
+		}
+		return strings.Join(pending, ","), nil
+	}
+	return answer, nil
+}
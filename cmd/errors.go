@@ -3,5 +3,8 @@ package cmd
 import "errors"
 
 var (
-	ErrWordFlagRequired = errors.New("word is required --word <word>")
+	ErrWordFlagRequired  = errors.New("word is required: --words <word,word>, --input <file> or -")
+	ErrTopicRequired     = errors.New("topic is required: --topic <topic>, --input <file> or -")
+	ErrPromptRequired    = errors.New("prompt is required: --prompt <prompt>, --input <file> or -")
+	ErrOutWithManyInputs = errors.New("--out can only be used with a single word")
 )
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/netr/haki/anki"
+	"github.com/netr/haki/prompt"
+)
+
+// maxDeckSampleNotes caps how many of a deck's most recent notes are read for tags and examples.
+const maxDeckSampleNotes = 200
+
+// deckSample is what the prompt uses from the chosen deck: its tags and style examples.
+type deckSample struct {
+	deck     string
+	tags     []string
+	examples []prompt.Example
+}
+
+// promptSample returns the sample of the chosen deck. Prompts are rendered for every chunk, section and
+// regeneration, so the deck is only read once until another deck is chosen.
+func (t *BasePlugin) promptSample() *deckSample {
+	if t.sample == nil || t.sample.deck != t.deckName {
+		notes := t.sampleDeck()
+		t.sample = &deckSample{deck: t.deckName, tags: deckTags(notes), examples: t.deckExamples(notes)}
+	}
+	return t.sample
+}
+
+// sampleDeck returns the most recently created notes in the chosen deck. The sample only improves
+// the prompt, so failures are logged and an empty sample is returned.
+func (t *BasePlugin) sampleDeck() []anki.NoteInfo {
+	if t.deckName == "" {
+		return nil
+	}
+	ids, err := t.ankiClient.Notes().Find(fmt.Sprintf(`"deck:%s"`, anki.EscapeSearchText(t.deckName)))
+	if err != nil || len(ids) == 0 {
+		return nil
+	}
+
+	// Note ids are creation timestamps, so the largest ids are the newest notes.
+	slices.Sort(ids)
+	if len(ids) > maxDeckSampleNotes {
+		ids = ids[len(ids)-maxDeckSampleNotes:]
+	}
+	infos, err := t.ankiClient.Notes().Info(ids...)
+	if err != nil {
+		slog.Warn("sample deck", slog.String("deck", t.deckName), slog.String("error", err.Error()))
+		return nil
+	}
+	return infos
+}
+
+// deckTags returns the tags used by the sampled notes.
+func deckTags(notes []anki.NoteInfo) []string {
+	var tags []string
+	for _, info := range notes {
+		for _, tag := range info.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+// deckExamples picks the best performing sampled notes as style examples, within the plugin's token budget.
+func (t *BasePlugin) deckExamples(notes []anki.NoteInfo) []prompt.Example {
+	if t.config.ExampleTokens <= 0 || len(notes) == 0 {
+		return nil
+	}
+
+	ranked := rankNotesByPerformance(notes, t.fetchReviewStats(notes))
+	examples := make([]prompt.Example, 0, len(ranked))
+	for _, n := range ranked {
+		fields := n.OrderedFields()
+		if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
+			continue
+		}
+		examples = append(examples, prompt.Example{Front: fields[0], Back: fields[1]})
+	}
+
+	examples = prompt.FitExamples(examples, t.config.ExampleTokens)
+	slog.Info("deck examples", slog.String("deck", t.deckName), slog.Int("count", len(examples)))
+	return examples
+}
+
+// reviewStats summarizes how well a note's cards have been reviewed.
+type reviewStats struct {
+	reps     int
+	lapses   int
+	interval int
+}
+
+// fetchReviewStats fetches the review statistics of the notes' cards, keyed by note id.
+// Without them, notes are ranked by recency.
+func (t *BasePlugin) fetchReviewStats(notes []anki.NoteInfo) map[float64]reviewStats {
+	var cardIDs []float64
+	for _, n := range notes {
+		cardIDs = append(cardIDs, n.Cards...)
+	}
+	if len(cardIDs) == 0 {
+		return nil
+	}
+
+	cards, err := t.ankiClient.Cards().Info(cardIDs...)
+	if err != nil {
+		slog.Warn("review stats", slog.String("deck", t.deckName), slog.String("error", err.Error()))
+		return nil
+	}
+
+	stats := make(map[float64]reviewStats, len(notes))
+	for _, c := range cards {
+		s := stats[c.NoteID]
+		s.reps += c.Reps
+		s.lapses += c.Lapses
+		s.interval = max(s.interval, c.Interval)
+		stats[c.NoteID] = s
+	}
+	return stats
+}
+
+// rankNotesByPerformance orders notes so the ones learned best come first: reviewed notes with the fewest
+// lapses per review and the longest interval, then unreviewed notes from newest to oldest.
+func rankNotesByPerformance(notes []anki.NoteInfo, stats map[float64]reviewStats) []anki.NoteInfo {
+	ranked := slices.Clone(notes)
+	slices.SortStableFunc(ranked, func(a, b anki.NoteInfo) int {
+		sa, sb := stats[a.NoteID], stats[b.NoteID]
+		if reviewedA, reviewedB := sa.reps > 0, sb.reps > 0; reviewedA != reviewedB {
+			if reviewedA {
+				return -1
+			}
+			return 1
+		}
+		if sa.reps > 0 {
+			if c := cmp.Compare(lapseRate(sa), lapseRate(sb)); c != 0 {
+				return c
+			}
+			if c := cmp.Compare(sb.interval, sa.interval); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(b.NoteID, a.NoteID)
+	})
+	return ranked
+}
+
+func lapseRate(s reviewStats) float64 {
+	return float64(s.lapses) / float64(s.reps)
+}
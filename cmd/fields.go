@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/netr/haki/ai"
+	"github.com/netr/haki/anki"
+)
+
+var (
+	ErrFieldMappingIncomplete = errors.New("field mapping needs both a front and a back field")
+	ErrFieldNotFound          = errors.New("field not found on note type")
+)
+
+// lowConfidenceTag is added to notes the model wasn't sure about, so they can be found and checked in Anki.
+const (
+	lowConfidenceTag       = "haki::low-confidence"
+	lowConfidenceThreshold = 0.5
+)
+
+// FieldMapping maps the attributes of a card onto the fields of a note type.
+// Attributes mapped to an empty field name are left out, except for extra, which is appended to the back.
+type FieldMapping struct {
+	Front  string `json:"front"`
+	Back   string `json:"back"`
+	Extra  string `json:"extra"`
+	Source string `json:"source"`
+	Audio  string `json:"audio"`
+	Image  string `json:"image"`
+}
+
+var (
+	basicFieldMapping = FieldMapping{Front: anki.FieldFront, Back: anki.FieldBack}
+	clozeFieldMapping = FieldMapping{Front: anki.FieldText, Back: anki.FieldBackExtra}
+	vocabFieldMapping = FieldMapping{Front: "Question", Back: "Definition", Audio: "Audio", Image: "Picture"}
+)
+
+// Fields returns the note fields for the card.
+func (m FieldMapping) Fields(c ai.AnkiCard) map[string]interface{} {
+	back := formatBack(c.Back)
+	extra := formatBack(c.Extra)
+
+	fields := map[string]interface{}{}
+	if m.Extra != "" {
+		fields[m.Extra] = extra
+	} else if strings.TrimSpace(extra) != "" {
+		if strings.TrimSpace(back) != "" {
+			back += "<br><br>"
+		}
+		back += extra
+	}
+	if m.Source != "" {
+		fields[m.Source] = c.Source
+	}
+	fields[m.Front] = c.Front
+	fields[m.Back] = back
+	return fields
+}
+
+// Validate checks that every mapped field exists in fieldNames, the fields of the target note type.
+func (m FieldMapping) Validate(fieldNames []string) error {
+	if m.Front == "" || m.Back == "" {
+		return ErrFieldMappingIncomplete
+	}
+
+	mapped := []struct{ attr, field string }{
+		{"front", m.Front},
+		{"back", m.Back},
+		{"extra", m.Extra},
+		{"source", m.Source},
+		{"audio", m.Audio},
+		{"image", m.Image},
+	}
+	for _, f := range mapped {
+		if f.field != "" && !slices.Contains(fieldNames, f.field) {
+			return fmt.Errorf("%s -> %q (available: %s): %w", f.attr, f.field, strings.Join(fieldNames, ", "), ErrFieldNotFound)
+		}
+	}
+	return nil
+}
+
+// cardTags returns the card's suggested tags in a form Anki accepts.
+func cardTags(c ai.AnkiCard) []string {
+	tags := make([]string, 0, len(c.Tags)+1)
+	for _, tag := range c.Tags {
+		if tag = anki.NormalizeTag(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	if c.Confidence > 0 && c.Confidence < lowConfidenceThreshold {
+		tags = append(tags, lowConfidenceTag)
+	}
+	return tags
+}
+
+// buildCardNote maps a card onto the given note type. Cloze cards always use Anki's Cloze note type,
+// and reversed cards use the reversed variant of Basic when the note type is Basic.
+func buildCardNote(deckName string, c ai.AnkiCard, modelName string, mapping FieldMapping) (*anki.NoteBuilder, error) {
+	switch c.Kind {
+	case ai.CardKindCloze:
+		if err := anki.ValidateCloze(c.Front); err != nil {
+			return nil, fmt.Errorf("build card note: %w", err)
+		}
+		modelName, mapping = anki.ModelCloze, clozeFieldMapping
+	case ai.CardKindReversed:
+		if modelName == anki.ModelBasic {
+			modelName = anki.ModelBasicReversed
+		}
+	}
+
+	return anki.NewNoteBuilder(deckName, modelName, mapping.Fields(c)).
+		WithTags(cardTags(c)...), nil
+}
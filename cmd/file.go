@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/netr/haki/ai"
+	"github.com/netr/haki/anki"
+	"github.com/netr/haki/document"
+)
+
+var (
+	ErrNoFilesFound        = errors.New("no Markdown or HTML files found")
+	ErrInvalidHeadingsMode = errors.New("invalid headings mode, expected tags or decks")
+)
+
+// Headings modes decide what a section's headings become: tags on its notes, or sub-decks of the chosen deck.
+const (
+	headingsTags  = "tags"
+	headingsDecks = "decks"
+)
+
+// fileSummaryLength is how much of a file's text is shown to the AI when choosing its deck.
+const fileSummaryLength = 500
+
+func NewFileCommand(apiKey, outputDir string, cfg CommandConfig, profiles []DeckProfile) *cli.Command {
+	return &cli.Command{
+		Name:      "file",
+		Usage:     "Generate Anki cards for every section of Markdown or HTML files.",
+		ArgsUsage: "--headings <tags|decks> " + generationArgsUsage + " <path or glob...>",
+		Flags:     append([]cli.Flag{newHeadingsFlag()}, generationFlags()...),
+		Action: actionFn(
+			NewFileAction(
+				apiKey,
+				"file",
+				outputDir,
+				cfg,
+				profiles,
+				append([]string{"headings"}, generationFlagNames...),
+			)),
+	}
+}
+
+func newHeadingsFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "headings",
+		Value: headingsTags,
+		Usage: "what section headings become: tags on the notes, or sub-decks of the chosen deck (tags, decks)",
+	}
+}
+
+type FileAction struct {
+	Action
+	outputDir string
+	config    CommandConfig
+	profiles  []DeckProfile
+}
+
+func NewFileAction(apiKey, name, outputDir string, cfg CommandConfig, profiles []DeckProfile, flags []string) *FileAction {
+	return &FileAction{
+		Action: Action{
+			flags:  flags,
+			apiKey: apiKey,
+			name:   name,
+		},
+		outputDir: outputDir,
+		config:    cfg.WithDefaults(name),
+		profiles:  profiles,
+	}
+}
+
+// TakesArgs makes the paths given after the flags part of Run's arguments.
+func (a FileAction) TakesArgs() bool {
+	return true
+}
+
+func (a FileAction) Run(args ...interface{}) error {
+	if len(args) < 1+len(generationFlagNames) {
+		return fmt.Errorf("action run: %w", ErrQueryRequired)
+	}
+	headings := args[0].(string)
+	if headings != headingsTags && headings != headingsDecks {
+		return fmt.Errorf("action run: %s: %w", headings, ErrInvalidHeadingsMode)
+	}
+	gen, err := parseGenerationArgs(args[1 : 1+len(generationFlagNames)])
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+	var patterns []string
+	for _, arg := range args[1+len(generationFlagNames):] {
+		patterns = append(patterns, arg.(string))
+	}
+	paths, err := expandPaths(patterns)
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+	model := a.config.model(gen.Overrides.Model)
+
+	run := NewJournalEntry(a.Name(), paths...)
+	defer recordRun(a.outputDir, run, gen.Debug)
+
+	opts := newPluginOptions(a.apiKey, a.outputDir, a.Name(), a.config, a.profiles, gen, run)
+	for _, path := range paths {
+		if err := runFile(a.apiKey, path, model, headings, gen.Debug, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandPaths turns the paths, globs and directories given on the command line into the documents they name.
+// Directories are searched recursively.
+func expandPaths(patterns []string) ([]string, error) {
+	var paths []string
+	add := func(path string) {
+		if document.Supported(path) && !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("expand paths (%s): %w", pattern, err)
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, fmt.Errorf("expand paths (%s): %w", match, err)
+			}
+			if !info.IsDir() {
+				add(match)
+				continue
+			}
+			err = filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() {
+					add(path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("expand paths (%s): %w", match, err)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return nil, ErrNoFilesFound
+	}
+	return paths, nil
+}
+
+// runFile chooses a deck for the file, then runs every section through the topic pipeline.
+func runFile(apiKey, path, model, headings string, skipSave bool, opts PluginOptions) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("run file: %w", err)
+	}
+	sections, err := document.Parse(path, data)
+	if err != nil {
+		return fmt.Errorf("run file: %w", err)
+	}
+	if len(sections) == 0 {
+		slog.Warn("file has no text", slog.String("path", path))
+		return nil
+	}
+
+	cardCreator, err := newCardCreatorFunc(apiKey)(model)
+	if err != nil {
+		return fmt.Errorf("new openai card creator (%s): %w", model, err)
+	}
+	opts.Run.Model = cardCreator.ModelName().String()
+
+	chooser := &TopicPlugin{BasePlugin: NewBasePlugin(cardCreator, opts)}
+	if !skipSave {
+		if err := chooser.Validate(); err != nil {
+			return fmt.Errorf("run file: %w", err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), aiTimeout)
+	deckName, err := chooser.ChooseDeck(ctx, fileSummary(path, sections))
+	cancel()
+	if err != nil {
+		return fmt.Errorf("run file (%s): %w", path, err)
+	}
+
+	storedAny := false
+	for _, section := range sections {
+		sectionOpts := opts
+		sectionOpts.Origin = sectionOrigin(path, section)
+		sectionDeck := deckName
+		if headings == headingsDecks {
+			sectionDeck = subDeck(deckName, section.Headings)
+		} else {
+			sectionOpts.Config.Tags = headingTags(opts.Config.Tags, section.Headings)
+		}
+		stored, _, err := runSection(cardCreator, sectionDeck, section, skipSave, sectionOpts)
+		if err != nil {
+			return fmt.Errorf("run file (%s): %w", path, err)
+		}
+		storedAny = storedAny || len(stored) > 0
+	}
+	if storedAny {
+		chooser.rememberDeck(deckName)
+	}
+	return nil
+}
+
+// runSection generates, reviews and stores the cards for one section of a document. It returns the ids of the
+// notes stored and of the notes in opts.Replace that weren't needed.
+func runSection(cardCreator ai.AnkiController, deckName string, section document.Section, skipSave bool, opts PluginOptions) (stored, unused []float64, err error) {
+	plugin := &TopicPlugin{BasePlugin: NewBasePlugin(cardCreator, opts)}
+	if skipSave {
+		if err := plugin.setDeck(deckName); err != nil {
+			return nil, nil, err
+		}
+	} else if _, err := plugin.useDeck(deckName, "section"); err != nil {
+		return nil, nil, err
+	}
+
+	query := section.Body
+	if heading := section.Heading(); heading != "" {
+		query = heading + "\n\n" + query
+	}
+	slog.Info("section", slog.String("origin", opts.Origin), slog.String("deck", deckName))
+
+	ctx, cancel := context.WithTimeout(context.Background(), plugin.GenerationTimeout(query))
+	defer cancel()
+	cards, err := plugin.GenerateAnkiCards(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !skipSave {
+		deckName, cards, err = plugin.ReviewAnkiCards(query, deckName, cards)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := plugin.StoreAnkiCards(deckName, cards); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	PrintCards(cards, true)
+	stored, unused = plugin.storedNotes()
+	return stored, unused, nil
+}
+
+// sectionOrigin names a section by its document and heading, e.g. "notes/tcp.md › TCP > Congestion control".
+func sectionOrigin(path string, section document.Section) string {
+	if heading := section.Heading(); heading != "" {
+		return path + " › " + heading
+	}
+	return path
+}
+
+// subDeck returns the sub-deck of deckName named after the headings.
+func subDeck(deckName string, headings []string) string {
+	for _, h := range headings {
+		deckName += "::" + strings.ReplaceAll(h, "::", ":")
+	}
+	return deckName
+}
+
+// headingTags returns tags with a tag for the headings added, e.g. "TCP::Congestion_control".
+func headingTags(tags, headings []string) []string {
+	if len(headings) == 0 {
+		return tags
+	}
+	return append(slices.Clone(tags), anki.NormalizeTag(strings.Join(headings, "::")))
+}
+
+// fileSummary describes a file by its name, headings and opening text, which is enough to pick a deck for it.
+func fileSummary(path string, sections []document.Section) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	for _, s := range sections {
+		if len(s.Headings) > 0 && len(s.Headings) <= 2 {
+			b.WriteString("\n" + s.Heading())
+		}
+	}
+	text := []rune(sections[0].Body)
+	if len(text) > fileSummaryLength {
+		text = text[:fileSummaryLength]
+	}
+	b.WriteString("\n\n" + string(text))
+	return b.String()
+}
@@ -6,11 +6,10 @@ import (
 
 func newWordsFlag() *cli.StringFlag {
 	return &cli.StringFlag{
-		Name:     "words",
-		Aliases:  []string{"w"},
-		Value:    "",
-		Required: true,
-		Usage:    "words to create a card for (comma separated)",
+		Name:    "words",
+		Aliases: []string{"w"},
+		Value:   "",
+		Usage:   "words to create a card for (comma separated)",
 	}
 }
 
@@ -46,7 +45,121 @@ func newModelFlag() *cli.StringFlag {
 	return &cli.StringFlag{
 		Name:    "model",
 		Aliases: []string{"m"},
-		Value:   "gpt-4o-2024-11-20",
-		Usage:   "ai model",
+		Value:   "",
+		Usage:   "ai model, overrides the config (default: " + defaultModel + ")",
+	}
+}
+
+func newYesFlag() *cli.BoolFlag {
+	return &cli.BoolFlag{
+		Name:    "yes",
+		Aliases: []string{"y"},
+		Value:   false,
+		Usage:   "skip confirmation prompts",
+	}
+}
+
+func newOnDuplicateFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "on-duplicate",
+		Value: string(DuplicateFail),
+		Usage: "what to do when a note already exists: skip, update, fail or allow",
+	}
+}
+
+func newNoteTypeFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "note-type",
+		Value: "",
+		Usage: "anki note type to store cards as, overrides the config",
+	}
+}
+
+func newPromptNameFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "prompt-name",
+		Value: "",
+		Usage: "prompt template to generate cards with, overrides the config",
+	}
+}
+
+func newCountFlag() *cli.IntFlag {
+	return &cli.IntFlag{
+		Name:    "count",
+		Aliases: []string{"n"},
+		Value:   0,
+		Usage:   "exact number of cards to create, 0 lets the model decide",
+	}
+}
+
+func newMaxCardsFlag() *cli.IntFlag {
+	return &cli.IntFlag{
+		Name:  "max-cards",
+		Value: 0,
+		Usage: "most cards to create, overrides the config",
+	}
+}
+
+func newLevelFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "level",
+		Value: "",
+		Usage: "audience to write for: beginner, intermediate or expert",
+	}
+}
+
+func newStyleFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "style",
+		Value: "",
+		Usage: "atomic for one fact per card, comprehensive for in-depth cards",
+	}
+}
+
+func newDeckFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "deck",
+		Value: "",
+		Usage: "deck to store cards in, skips deck routing",
+	}
+}
+
+func newAllowNewDeckFlag() *cli.BoolFlag {
+	return &cli.BoolFlag{
+		Name:  "allow-new-deck",
+		Value: false,
+		Usage: "create decks suggested by the AI without asking",
+	}
+}
+
+func newReviewFlag() *cli.BoolFlag {
+	return &cli.BoolFlag{
+		Name:  "review",
+		Value: false,
+		Usage: "review each card before it is stored, even outside a terminal",
+	}
+}
+
+func newNoReviewFlag() *cli.BoolFlag {
+	return &cli.BoolFlag{
+		Name:  "no-review",
+		Value: false,
+		Usage: "store cards without reviewing them",
+	}
+}
+
+func newCriticFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "critic",
+		Value: "",
+		Usage: "check cards against spaced repetition best practices: off, auto to apply the fixes, or review to show them",
+	}
+}
+
+func newGroundedFlag() *cli.BoolFlag {
+	return &cli.BoolFlag{
+		Name:  "grounded",
+		Value: false,
+		Usage: "only create cards backed by a quote from the input, and check the quotes",
 	}
 }
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/netr/haki/ai"
+	"github.com/netr/haki/prompt"
+)
+
+var ErrInvalidCardCount = errors.New("invalid card count, expected a number of 0 or more")
+
+// maxTopUps is how many times the model is asked again when it returns fewer cards than requested.
+const maxTopUps = 2
+
+// generationFlagNames are the flags shared by every command that generates cards, in the order
+// parseGenerationArgs expects their values.
+var generationFlagNames = []string{
+	"service", "model", "debug", "on-duplicate", "note-type", "prompt-name", "count", "max-cards", "level", "style",
+	"deck", "allow-new-deck", "review", "no-review", "critic", "grounded",
+}
+
+const generationArgsUsage = "--service <service> --model <model> --debug --on-duplicate <policy> --note-type <note type> " +
+	"--prompt-name <prompt> --count <n> --max-cards <n> --level <level> --style <style> --deck <deck> --allow-new-deck " +
+	"--review --no-review --critic <mode> --grounded"
+
+func generationFlags() []cli.Flag {
+	return []cli.Flag{
+		newServiceFlag(),
+		newModelFlag(),
+		newDebugFlag(),
+		newOnDuplicateFlag(),
+		newNoteTypeFlag(),
+		newPromptNameFlag(),
+		newCountFlag(),
+		newMaxCardsFlag(),
+		newLevelFlag(),
+		newStyleFlag(),
+		newDeckFlag(),
+		newAllowNewDeckFlag(),
+		newReviewFlag(),
+		newNoReviewFlag(),
+		newCriticFlag(),
+		newGroundedFlag(),
+	}
+}
+
+// generationArgs are the parsed values of the generation flags.
+type generationArgs struct {
+	Service     string
+	Debug       bool
+	OnDuplicate DuplicatePolicy
+	Overrides   Overrides
+}
+
+// parseGenerationArgs parses the values of the generation flags, given in the order of generationFlagNames.
+func parseGenerationArgs(args []interface{}) (generationArgs, error) {
+	v := make([]string, len(generationFlagNames))
+	for i := range v {
+		if i < len(args) {
+			v[i], _ = args[i].(string)
+		}
+	}
+
+	onDuplicate, err := ParseDuplicatePolicy(v[3])
+	if err != nil {
+		return generationArgs{}, err
+	}
+	overrides := Overrides{
+		Model:        v[1],
+		NoteType:     v[4],
+		PromptName:   v[5],
+		Deck:         v[10],
+		AllowNewDeck: v[11] == "true",
+		Review:       reviewPolicyFlags(v[12], v[13]),
+		Grounded:     v[15] == "true",
+	}
+	if critic := v[14]; critic != "" {
+		if overrides.Critic, err = ParseCriticMode(critic); err != nil {
+			return generationArgs{}, err
+		}
+	}
+	if err := overrides.setGeneration(v[6], v[7], v[8], v[9]); err != nil {
+		return generationArgs{}, err
+	}
+	return generationArgs{Service: v[0], Debug: v[2] == "true", OnDuplicate: onDuplicate, Overrides: overrides}, nil
+}
+
+// setGeneration parses the --count, --max-cards, --level and --style flags into the overrides.
+func (o *Overrides) setGeneration(count, maxCards, level, style string) error {
+	var err error
+	if o.Count, err = parseCardCount(count); err != nil {
+		return fmt.Errorf("count: %w", err)
+	}
+	if o.MaxCards, err = parseCardCount(maxCards); err != nil {
+		return fmt.Errorf("max cards: %w", err)
+	}
+	if o.Level, err = prompt.ParseLevel(level); err != nil {
+		return err
+	}
+	if o.Style, err = prompt.ParseStyle(style); err != nil {
+		return err
+	}
+	return nil
+}
+
+func parseCardCount(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s: %w", s, ErrInvalidCardCount)
+	}
+	return n, nil
+}
+
+// cardLimits returns the exact number of cards wanted and the most cards allowed. Zero means no constraint.
+// The count is capped by the maximum.
+func (t *BasePlugin) cardLimits() (count, maxCards int) {
+	count, maxCards = t.overrides.Count, t.config.MaxCards
+	if t.overrides.MaxCards > 0 {
+		maxCards = t.overrides.MaxCards
+	}
+	if maxCards > 0 && count > maxCards {
+		count = maxCards
+	}
+	return count, maxCards
+}
+
+// audience returns the level and style to write cards for, with the flags taking precedence over the config.
+func (t *BasePlugin) audience() (prompt.Level, prompt.Style, error) {
+	level, style := t.overrides.Level, t.overrides.Style
+	var err error
+	if level == "" {
+		if level, err = prompt.ParseLevel(t.config.Level); err != nil {
+			return "", "", fmt.Errorf("config: %w", err)
+		}
+	}
+	if style == "" {
+		if style, err = prompt.ParseStyle(t.config.Style); err != nil {
+			return "", "", fmt.Errorf("config: %w", err)
+		}
+	}
+	return level, style, nil
+}
+
+// topUpCards asks the model for the cards missing from a run that came back short of the requested count.
+func (t *BasePlugin) topUpCards(ctx context.Context, query string, cards []ai.AnkiCard, count int) ([]ai.AnkiCard, error) {
+	for attempt := 0; len(cards) < count && attempt < maxTopUps; attempt++ {
+		missing := count - len(cards)
+		slog.Info("requesting more cards", slog.Int("count", len(cards)), slog.Int("missing", missing))
+
+		systemPrompt, err := t.renderPrompt(missing)
+		if err != nil {
+			return nil, err
+		}
+		more, err := t.ankiAI.GenerateAnkiCards(ctx, t.deckName, topUpQuery(query, cards), systemPrompt)
+		if err != nil {
+			return nil, err
+		}
+		if len(more) == 0 {
+			break
+		}
+		cards = append(cards, more...)
+	}
+	if len(cards) < count {
+		slog.Warn("fewer cards than requested", slog.Int("count", len(cards)), slog.Int("requested", count))
+	}
+	return cards, nil
+}
+
+// topUpQuery lists the cards already created so the model doesn't repeat them.
+func topUpQuery(query string, cards []ai.AnkiCard) string {
+	var b strings.Builder
+	b.WriteString(query)
+	b.WriteString("\n\nThese cards were already created, don't repeat them:")
+	for _, c := range cards {
+		b.WriteString("\n- ")
+		b.WriteString(c.Front)
+	}
+	return b.String()
+}
+
+// limitCards trims cards to the requested count, or failing that to the maximum.
+func limitCards(cards []ai.AnkiCard, count, maxCards int) []ai.AnkiCard {
+	limit := count
+	if limit == 0 {
+		limit = maxCards
+	}
+	if limit > 0 && len(cards) > limit {
+		slog.Info("trimming cards", slog.Int("count", len(cards)), slog.Int("max", limit))
+		return cards[:limit]
+	}
+	return cards
+}
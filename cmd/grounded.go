@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/netr/haki/ai"
+	"github.com/netr/haki/grounding"
+)
+
+var ErrInvalidUnsupportedAction = errors.New("invalid unsupported cards action, expected flag or drop")
+
+// unsupportedTag marks grounded cards whose cited quote couldn't be found in the input.
+const unsupportedTag = "haki::unsupported"
+
+const (
+	unsupportedFlag = "flag"
+	unsupportedDrop = "drop"
+)
+
+// grounded reports whether cards must cite the input, with the flag taking precedence over the config.
+func (t *BasePlugin) grounded() bool {
+	return t.overrides.Grounded || t.config.Grounded
+}
+
+// groundCards checks every card's cited quote against the input. Supported cards get the verbatim span from
+// the input as their source; unsupported cards are tagged, or dropped when the config asks for it. kept holds
+// the position each returned card had in cards.
+func (t *BasePlugin) groundCards(input string, cards []ai.AnkiCard) (grounded []ai.AnkiCard, kept []int, err error) {
+	action := strings.ToLower(t.config.UnsupportedCards)
+	switch action {
+	case "":
+		action = unsupportedFlag
+	case unsupportedFlag, unsupportedDrop:
+	default:
+		return nil, nil, fmt.Errorf("config: %s: %w", t.config.UnsupportedCards, ErrInvalidUnsupportedAction)
+	}
+
+	for i, c := range cards {
+		match, ok := grounding.Verify(input, c.Source, grounding.DefaultThreshold)
+		if !ok {
+			slog.Warn("unsupported card",
+				slog.String("front", c.Front),
+				slog.String("source", c.Source),
+				slog.Float64("score", match.Score),
+				slog.String("action", action),
+			)
+			if action == unsupportedDrop {
+				continue
+			}
+			c.Tags = append(c.Tags, unsupportedTag)
+			grounded = append(grounded, c)
+			kept = append(kept, i)
+			continue
+		}
+
+		c.Source = match.Text
+		grounded = append(grounded, c)
+		kept = append(kept, i)
+	}
+	return grounded, kept, nil
+}
+
+// citeCards records where each card came from: the origin of the input and, for grounded cards, the span
+// they are based on. Without a source field, the citation goes on the back so it isn't lost.
+func (t *BasePlugin) citeCards(cards []ai.AnkiCard) []ai.AnkiCard {
+	grounded := t.grounded()
+	if t.origin == "" && !grounded {
+		return cards
+	}
+	_, fields := t.noteType()
+
+	for i, c := range cards {
+		var parts []string
+		if t.origin != "" {
+			parts = append(parts, t.origin)
+		}
+		if grounded && c.Source != "" && !slices.Contains(c.Tags, unsupportedTag) {
+			if fields.Source == "" {
+				parts = append(parts, fmt.Sprintf("\"%s\"", c.Source))
+			} else {
+				parts = append(parts, c.Source)
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+
+		citation := strings.Join(parts, ": ")
+		if fields.Source != "" {
+			cards[i].Source = citation
+			continue
+		}
+		cards[i].Extra = strings.TrimSpace(c.Extra + fmt.Sprintf("\n<i>Source: %s</i>", citation))
+	}
+	return cards
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/netr/haki/anki"
+	"github.com/netr/haki/highlights"
+)
+
+var (
+	ErrCSVRequired      = errors.New("csv file is required")
+	ErrInvalidBatchSize = errors.New("invalid batch size, expected a number of 1 or more")
+)
+
+// defaultBatchSize keeps a batch of highlights well within a single chunk of input.
+const defaultBatchSize = 20
+
+func newReadwiseImportCommand(apiKey, outputDir string, config func(command string) CommandConfig, profiles []DeckProfile) *cli.Command {
+	return &cli.Command{
+		Name:      "readwise",
+		Usage:     "Generate Anki cards from the highlights of a Readwise CSV export.",
+		ArgsUsage: "--batch-size <n> " + generationArgsUsage + " <file.csv>",
+		Flags:     append([]cli.Flag{newBatchSizeFlag()}, generationFlags()...),
+		Action: actionFn(
+			NewHighlightsImportAction(
+				apiKey,
+				"readwise",
+				outputDir,
+				config("readwise"),
+				profiles,
+				append([]string{"batch-size"}, generationFlagNames...),
+			)),
+	}
+}
+
+func newCSVImportCommand(apiKey, outputDir string, config func(command string) CommandConfig, profiles []DeckProfile) *cli.Command {
+	return &cli.Command{
+		Name:      "csv",
+		Usage:     "Generate Anki cards from highlights in any CSV file, with its columns mapped by --columns.",
+		ArgsUsage: "--columns <highlight=Quote,title=Book> --batch-size <n> " + generationArgsUsage + " <file.csv>",
+		Flags:     append([]cli.Flag{newColumnsFlag(), newBatchSizeFlag()}, generationFlags()...),
+		Action: actionFn(
+			NewHighlightsImportAction(
+				apiKey,
+				"csv",
+				outputDir,
+				config("csv"),
+				profiles,
+				append([]string{"columns", "batch-size"}, generationFlagNames...),
+			)),
+	}
+}
+
+func newColumnsFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "columns",
+		Value: "",
+		Usage: "columns to read the highlight, note, title, author and location from, like highlight=Quote,title=Book",
+	}
+}
+
+func newBatchSizeFlag() *cli.IntFlag {
+	return &cli.IntFlag{
+		Name:  "batch-size",
+		Value: defaultBatchSize,
+		Usage: "number of highlights from the same book sent to the AI together",
+	}
+}
+
+// HighlightsImportAction imports highlights from CSV: Readwise's export, or any CSV when named csv.
+type HighlightsImportAction struct {
+	Action
+	outputDir string
+	config    CommandConfig
+	profiles  []DeckProfile
+}
+
+func NewHighlightsImportAction(apiKey, name, outputDir string, cfg CommandConfig, profiles []DeckProfile, flags []string) *HighlightsImportAction {
+	return &HighlightsImportAction{
+		Action: Action{
+			flags:  flags,
+			apiKey: apiKey,
+			name:   name,
+		},
+		outputDir: outputDir,
+		config:    cfg.WithDefaults(name),
+		profiles:  profiles,
+	}
+}
+
+// TakesArgs makes the file given after the flags part of Run's arguments.
+func (a HighlightsImportAction) TakesArgs() bool {
+	return true
+}
+
+func (a HighlightsImportAction) Run(args ...interface{}) error {
+	columns := highlights.ReadwiseColumns
+	if a.Name() == "csv" {
+		mapping := args[0].(string)
+		if mapping == "" {
+			mapping = a.config.Columns
+		}
+		var err error
+		if columns, err = highlights.ParseColumns(mapping, highlights.DefaultColumns); err != nil {
+			return fmt.Errorf("action run: %w", err)
+		}
+		args = args[1:]
+	}
+	if len(args) < 2+len(generationFlagNames) {
+		return fmt.Errorf("action run: %w", ErrCSVRequired)
+	}
+	batchSize, err := strconv.Atoi(args[0].(string))
+	if err != nil || batchSize < 1 {
+		return fmt.Errorf("action run: %w", ErrInvalidBatchSize)
+	}
+	gen, err := parseGenerationArgs(args[1 : 1+len(generationFlagNames)])
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+	path := args[1+len(generationFlagNames)].(string)
+
+	hs, err := readHighlights(path, columns)
+	if err != nil {
+		return fmt.Errorf("action run (%s): %w", path, err)
+	}
+
+	run := NewJournalEntry(a.Name(), path)
+	defer recordRun(a.outputDir, run, gen.Debug)
+
+	imports := NewImportLog(a.outputDir)
+	batches, err := highlightBatches(a.Name(), hs, batchSize, imports)
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+	if len(batches) == 0 {
+		fmt.Println("No new highlights to import.")
+		return nil
+	}
+
+	imp := highlightImporter{
+		apiKey:    a.apiKey,
+		outputDir: a.outputDir,
+		name:      a.Name(),
+		config:    a.config,
+		profiles:  a.profiles,
+		gen:       gen,
+		run:       run,
+		imports:   imports,
+	}
+	for _, b := range batches {
+		if err := imp.importBatch(b); err != nil {
+			return fmt.Errorf("import %s: %w", a.Name(), err)
+		}
+	}
+	return nil
+}
+
+// readHighlights reads the highlights of the CSV file at path.
+func readHighlights(path string, columns highlights.Columns) ([]highlights.Highlight, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return highlights.ReadCSV(f, columns)
+}
+
+// highlightBatches splits the highlights that haven't been imported yet into batches of at most size highlights
+// from the same title.
+func highlightBatches(importer string, hs []highlights.Highlight, size int, imports *ImportLog) ([]highlightBatch, error) {
+	var batches []highlightBatch
+	for _, h := range hs {
+		key := "highlight:" + h.Key()
+		done, err := imports.Imported(importer, key)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			continue
+		}
+
+		if n := len(batches); n == 0 || batches[n-1].Title != h.Title || len(batches[n-1].Keys) >= size {
+			batches = append(batches, highlightBatch{Title: h.Title, Author: h.Author})
+		}
+		b := &batches[len(batches)-1]
+		text := h.Text
+		if h.Note != "" {
+			text += "\nNote: " + h.Note
+		}
+		b.Texts = append(b.Texts, text)
+		b.Keys = append(b.Keys, key)
+	}
+	return batches, nil
+}
+
+// highlightBatch is highlights from one book that go through the topic pipeline together.
+type highlightBatch struct {
+	Title  string
+	Author string
+	Texts  []string
+	// Keys identify the highlights in the import log.
+	Keys []string
+}
+
+// highlightImporter runs batches of highlights through the topic pipeline and records them in the import log.
+type highlightImporter struct {
+	apiKey    string
+	outputDir string
+	name      string
+	config    CommandConfig
+	profiles  []DeckProfile
+	gen       generationArgs
+	run       *JournalEntry
+	imports   *ImportLog
+}
+
+// importBatch generates, reviews and stores the cards for a batch, tagged with its book. The batch is only
+// recorded as imported once its cards are stored.
+func (imp highlightImporter) importBatch(b highlightBatch) error {
+	opts := importOptions(imp.apiKey, imp.outputDir, "topic", imp.config, imp.profiles, b.Title, imp.gen, imp.run)
+	opts.Origin = b.Title
+	if b.Author != "" {
+		opts.Origin += " by " + b.Author
+	}
+	model := imp.config.model(imp.gen.Overrides.Model)
+
+	slog.Info("importing highlights", slog.String("importer", imp.name), slog.String("book", b.Title), slog.Int("count", len(b.Texts)))
+	if err := runTopic(imp.apiKey, strings.Join(b.Texts, "\n\n"), model, imp.gen.Debug, opts); err != nil {
+		return err
+	}
+	if imp.gen.Debug {
+		return nil
+	}
+	return imp.imports.Add(imp.name, b.Keys...)
+}
+
+// importOptions returns the options for running imported input through the pipeline of command, tagging every
+// note with the book it came from.
+func importOptions(apiKey, outputDir, command string, cfg CommandConfig, profiles []DeckProfile, book string, gen generationArgs, run *JournalEntry) PluginOptions {
+	if tag := anki.NormalizeTag(book); tag != "" {
+		cfg.Tags = append(slices.Clone(cfg.Tags), tag)
+	}
+	return newPluginOptions(apiKey, outputDir, command, cfg, profiles, gen, run)
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/netr/haki/anki"
+)
+
+func NewHistoryCommand(hakiDir string) *cli.Command {
+	return &cli.Command{
+		Name:   "history",
+		Usage:  "List previous runs and the notes they created.",
+		Action: actionHistory(hakiDir),
+	}
+}
+
+func actionHistory(hakiDir string) func(cCtx *cli.Context) error {
+	return func(_ *cli.Context) error {
+		entries, err := NewJournal(hakiDir).List()
+		if err != nil {
+			return fmt.Errorf("history: %w", err)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No runs recorded yet.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "RUN\tDATE\tCOMMAND\tARGS\tMODEL\tNOTES\tMEDIA\tSTATUS")
+		for _, e := range entries {
+			status := "active"
+			if e.IsUndone() {
+				status = "undone"
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+				e.ShortID(),
+				e.CreatedAt.Format(time.DateTime),
+				e.Command,
+				strings.Join(e.Args, ", "),
+				e.Model,
+				len(e.NoteIDs),
+				len(e.Media),
+				status,
+			)
+		}
+		return w.Flush()
+	}
+}
+
+func NewUndoCommand(hakiDir string) *cli.Command {
+	return &cli.Command{
+		Name:      "undo",
+		Usage:     "Delete the notes and media created by a previous run.",
+		ArgsUsage: "<run> [--yes]",
+		Flags:     []cli.Flag{newYesFlag()},
+		Action:    actionUndo(hakiDir),
+	}
+}
+
+func actionUndo(hakiDir string) func(cCtx *cli.Context) error {
+	return func(cCtx *cli.Context) error {
+		if err := runUndo(hakiDir, cCtx.Args().First(), cCtx.Bool("yes")); err != nil {
+			slog.Error("run", slog.String("action", "undo"), slog.String("error", err.Error()))
+			return err
+		}
+		return nil
+	}
+}
+
+func runUndo(hakiDir, runID string, skipConfirm bool) error {
+	journal := NewJournal(hakiDir)
+	entry, err := journal.Find(runID)
+	if err != nil {
+		return fmt.Errorf("undo: %w", err)
+	}
+	if entry.IsUndone() {
+		return fmt.Errorf("undo %s: %w", entry.ShortID(), ErrRunUndone)
+	}
+
+	// Runs recorded before media was deduplicated can list a file more than once.
+	media := slices.Compact(slices.Sorted(slices.Values(entry.Media)))
+	fmt.Printf("Run %s (%s %s) created %d note(s) and %d media file(s).\n",
+		entry.ShortID(), entry.Command, strings.Join(entry.Args, ", "), len(entry.NoteIDs), len(media))
+	if !skipConfirm {
+		ok, err := confirm("Delete them from Anki?")
+		if err != nil {
+			return fmt.Errorf("undo: %w", err)
+		}
+		if !ok {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	client := newAnkiClient()
+	if err := client.Notes().Delete(entry.NoteIDs...); err != nil {
+		return fmt.Errorf("undo: %w", err)
+	}
+	deleted := 0
+	for _, m := range media {
+		// Media is named after the word, so notes from other runs of the same word can still use it.
+		ids, err := client.Notes().Find(mediaQuery(m))
+		if err != nil {
+			slog.Error("undo: find media references", slog.String("file", m), slog.String("error", err.Error()))
+			continue
+		}
+		if len(ids) > 0 {
+			slog.Info("undo: media still used", slog.String("file", m), slog.Int("notes", len(ids)))
+			continue
+		}
+		if err := client.Media().Delete(m); err != nil {
+			// The notes are already gone, so keep going and clean up as much media as we can.
+			slog.Error("undo: delete media", slog.String("file", m), slog.String("error", err.Error()))
+			continue
+		}
+		deleted++
+	}
+
+	now := time.Now()
+	entry.UndoneAt = &now
+	if err := journal.Record(entry); err != nil {
+		return fmt.Errorf("undo: %w", err)
+	}
+
+	fmt.Printf("Deleted %d note(s) and %d media file(s).\n", len(entry.NoteIDs), deleted)
+	return nil
+}
+
+// mediaQuery returns an Anki search query that finds the notes whose fields refer to the media file.
+func mediaQuery(fileName string) string {
+	return fmt.Sprintf(`"%s"`, anki.EscapeSearchText(fileName))
+}
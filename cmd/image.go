@@ -17,16 +17,17 @@ func NewImageCommand(apiKey, outputDir string) *cli.Command {
 	return &cli.Command{
 		Name:      "image",
 		Usage:     "GenerateAnkiCards an image for the specified text.",
-		ArgsUsage: "--prompt <prompt>",
+		ArgsUsage: "--prompt <prompt> --input <file> [--debug] [-]",
 		Flags: []cli.Flag{
 			newPromptFlag(),
+			newInputFlag(),
 			newDebugFlag(),
 		},
 		Action: actionFn(
 			NewImageAction(
 				apiKey,
 				outputDir,
-				[]string{"prompt", "debug"},
+				[]string{"prompt", "input", "debug"},
 			)),
 	}
 }
@@ -43,24 +44,42 @@ func NewImageAction(apiKey, outputDir string, flags []string) *ImageAction {
 	}
 }
 
+// TakesArgs makes a "-" given after the flags read words from stdin.
+func (i ImageAction) TakesArgs() bool {
+	return true
+}
+
 func (i ImageAction) Run(args ...interface{}) error {
-	if len(args) < 1 {
+	if len(args) < 3 {
 		return fmt.Errorf("action run (%s): %w", i.Name(), ErrQueryRequired)
 	}
-	prompt := fmt.Sprintf("Please create an illustration for the word \"%s\" to help visually represent its meaning for my Anki card.", args[0].(string))
-	outPath := fmt.Sprintf("%s/data/%s.webp", i.OutputDir, uuid.NewString())
-	debug := args[1].(string)
+	words, err := inputItems(args[1].(string), args[3:])
+	if err != nil {
+		return fmt.Errorf("action run (%s): %w", i.Name(), err)
+	}
+	if word := args[0].(string); word != "" {
+		words = append([]string{word}, words...)
+	}
+	if len(words) == 0 {
+		return fmt.Errorf("action run (%s): %w", i.Name(), ErrPromptRequired)
+	}
+	debug := args[2].(string)
 
 	skipSave := false
 	if debug == "true" {
 		skipSave = true
 	}
 
-	fmt.Println("Creating image with prompt:", prompt)
-	fmt.Println("Skip save?: ", skipSave)
+	for _, word := range words {
+		prompt := fmt.Sprintf("Please create an illustration for the word \"%s\" to help visually represent its meaning for my Anki card.", word)
+		outPath := fmt.Sprintf("%s/data/%s.webp", i.OutputDir, uuid.NewString())
 
-	if err := runImage(i.apiKey, prompt, outPath, skipSave); err != nil {
-		return fmt.Errorf("action run (%s): %w", i.Name(), err)
+		fmt.Println("Creating image with prompt:", prompt)
+		fmt.Println("Skip save?: ", skipSave)
+
+		if err := runImage(i.apiKey, prompt, outPath, skipSave); err != nil {
+			return fmt.Errorf("action run (%s): %w", i.Name(), err)
+		}
 	}
 	return nil
 }
@@ -88,10 +107,9 @@ func runImage(apiKey, prompt, outPath string, skipSave bool) error {
 
 func newPromptFlag() *cli.StringFlag {
 	return &cli.StringFlag{
-		Name:     "prompt",
-		Aliases:  []string{"p"},
-		Value:    "",
-		Required: true,
-		Usage:    "prompt to create image with",
+		Name:    "prompt",
+		Aliases: []string{"p"},
+		Value:   "",
+		Usage:   "prompt to create image with",
 	}
 }
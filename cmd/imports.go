@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// NewImportCommand groups the importers, which generate cards from what other tools have collected.
+// config returns the config of the command an importer runs its input through.
+func NewImportCommand(apiKey, outputDir string, config func(command string) CommandConfig, profiles []DeckProfile) *cli.Command {
+	return &cli.Command{
+		Name:  "import",
+		Usage: "Generate Anki cards from highlights, looked up words and books collected elsewhere.",
+		Subcommands: []*cli.Command{
+			newKindleImportCommand(apiKey, outputDir, config, profiles),
+			newEPUBImportCommand(apiKey, outputDir, config, profiles),
+			newReadwiseImportCommand(apiKey, outputDir, config, profiles),
+			newCSVImportCommand(apiKey, outputDir, config, profiles),
+			newSubsImportCommand(apiKey, outputDir, config, profiles),
+		},
+	}
+}
+
+// ImportLog remembers what has been imported, so running an import again only processes what is new.
+// It is stored as json in the haki directory, keyed by importer and then by item.
+type ImportLog struct {
+	path string
+}
+
+func NewImportLog(hakiDir string) *ImportLog {
+	return &ImportLog{path: filepath.Join(hakiDir, "imports.json")}
+}
+
+func (l *ImportLog) load() (map[string]map[string]time.Time, error) {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]map[string]time.Time{}, nil
+		}
+		return nil, fmt.Errorf("read import log: %w", err)
+	}
+
+	imported := map[string]map[string]time.Time{}
+	if err := json.Unmarshal(data, &imported); err != nil {
+		return nil, fmt.Errorf("decode import log: %w", err)
+	}
+	return imported, nil
+}
+
+// Imported reports whether the importer has already imported the item with the given key.
+func (l *ImportLog) Imported(importer, key string) (bool, error) {
+	imported, err := l.load()
+	if err != nil {
+		return false, err
+	}
+	_, ok := imported[importer][key]
+	return ok, nil
+}
+
+// Add records that the importer has imported the items with the given keys.
+func (l *ImportLog) Add(importer string, keys ...string) error {
+	imported, err := l.load()
+	if err != nil {
+		return err
+	}
+	if imported[importer] == nil {
+		imported[importer] = map[string]time.Time{}
+	}
+	now := time.Now()
+	for _, key := range keys {
+		imported[importer][key] = now
+	}
+
+	data, err := json.MarshalIndent(imported, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode import log: %w", err)
+	}
+	if err := os.WriteFile(l.path, data, 0644); err != nil {
+		return fmt.Errorf("write import log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// stdinArg is the argument that reads items from stdin, as in grep ... | haki vocab -.
+const stdinArg = "-"
+
+func newInputFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:    "input",
+		Aliases: []string{"i"},
+		Value:   "",
+		Usage:   "file of items to create cards for, one per line, or - for stdin. Blank lines and # comments are skipped",
+	}
+}
+
+// inputItems returns the items read from the --input file and the positional arguments. An input or argument
+// of "-" reads stdin, and any other argument is an item itself.
+func inputItems(input string, args []interface{}) ([]string, error) {
+	var items []string
+	if input != "" {
+		read, err := readItemsFile(input)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, read...)
+	}
+	for _, arg := range args {
+		arg := strings.TrimSpace(arg.(string))
+		switch arg {
+		case "":
+		case stdinArg:
+			read, err := readStdinItems()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, read...)
+		default:
+			items = append(items, arg)
+		}
+	}
+	return items, nil
+}
+
+// readItemsFile reads the items in path, or in stdin when path is "-".
+func readItemsFile(path string) ([]string, error) {
+	if path == stdinArg {
+		return readStdinItems()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read input: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	items, err := readItems(f)
+	if err != nil {
+		return nil, fmt.Errorf("read input (%s): %w", path, err)
+	}
+	return items, nil
+}
+
+// readStdinItems reads the items in stdin through the reader questions share. Stdin is then used up, so
+// questions can't be asked anymore.
+func readStdinItems() ([]string, error) {
+	stdinItems = true
+	items, err := readItems(stdin)
+	if err != nil {
+		return nil, fmt.Errorf("read stdin: %w", err)
+	}
+	return items, nil
+}
+
+// readItems reads one item per line, skipping blank lines and lines starting with #.
+func readItems(r io.Reader) ([]string, error) {
+	var items []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		items = append(items, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
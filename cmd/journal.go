@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRunNotFound   = errors.New("run not found")
+	ErrRunAmbiguous  = errors.New("run id matches more than one run")
+	ErrRunUndone     = errors.New("run has already been undone")
+	ErrRunIDRequired = errors.New("run id is required")
+)
+
+// JournalEntry records everything a single invocation of haki created in Anki, so it can be rolled back later.
+type JournalEntry struct {
+	ID        string     `json:"id"`
+	Command   string     `json:"command"`
+	Args      []string   `json:"args"`
+	Model     string     `json:"model"`
+	Prompt    string     `json:"prompt,omitempty"`
+	NoteIDs   []float64  `json:"note_ids"`
+	Media     []string   `json:"media"`
+	CreatedAt time.Time  `json:"created_at"`
+	UndoneAt  *time.Time `json:"undone_at,omitempty"`
+}
+
+func NewJournalEntry(command string, args ...string) *JournalEntry {
+	return &JournalEntry{
+		ID:        uuid.NewString(),
+		Command:   command,
+		Args:      args,
+		NoteIDs:   []float64{},
+		Media:     []string{},
+		CreatedAt: time.Now(),
+	}
+}
+
+// AddNote records a note id and the media filenames that were stored along with it.
+// It is safe to call on a nil entry, which allows plugins to be used without a journal.
+func (e *JournalEntry) AddNote(id float64, media ...string) {
+	if e == nil {
+		return
+	}
+	e.NoteIDs = append(e.NoteIDs, id)
+	// Every card of a word shares its media, so each file is only recorded once.
+	for _, m := range media {
+		if m != "" && !slices.Contains(e.Media, m) {
+			e.Media = append(e.Media, m)
+		}
+	}
+}
+
+// IsEmpty reports whether the run stored neither notes nor media.
+func (e *JournalEntry) IsEmpty() bool {
+	return len(e.NoteIDs) == 0 && len(e.Media) == 0
+}
+
+// ShortID returns the first block of the run id, which is enough to identify a run in practice.
+func (e *JournalEntry) ShortID() string {
+	id, _, _ := strings.Cut(e.ID, "-")
+	return id
+}
+
+func (e *JournalEntry) IsUndone() bool {
+	return e.UndoneAt != nil
+}
+
+// Journal is the on-disk list of runs, stored as json in the haki directory.
+type Journal struct {
+	path string
+}
+
+func NewJournal(hakiDir string) *Journal {
+	return &Journal{path: filepath.Join(hakiDir, "journal.json")}
+}
+
+// List returns every recorded run, oldest first.
+func (j *Journal) List() ([]*JournalEntry, error) {
+	data, err := os.ReadFile(j.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []*JournalEntry{}, nil
+		}
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+
+	var entries []*JournalEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("decode journal: %w", err)
+	}
+	return entries, nil
+}
+
+// Record adds the entry to the journal, replacing any existing entry with the same id.
+func (j *Journal) Record(entry *JournalEntry) error {
+	entries, err := j.List()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i, e := range entries {
+		if e.ID == entry.ID {
+			entries[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
+		entries = append(entries, entry)
+	}
+	return j.save(entries)
+}
+
+// Find returns the run whose id starts with the given prefix.
+func (j *Journal) Find(prefix string) (*JournalEntry, error) {
+	if prefix == "" {
+		return nil, ErrRunIDRequired
+	}
+	entries, err := j.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var found *JournalEntry
+	for _, e := range entries {
+		if strings.HasPrefix(e.ID, prefix) {
+			if found != nil {
+				return nil, fmt.Errorf("%s: %w", prefix, ErrRunAmbiguous)
+			}
+			found = e
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%s: %w", prefix, ErrRunNotFound)
+	}
+	return found, nil
+}
+
+func (j *Journal) save(entries []*JournalEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode journal: %w", err)
+	}
+	if err := os.WriteFile(j.path, data, 0644); err != nil {
+		return fmt.Errorf("write journal: %w", err)
+	}
+	return nil
+}
+
+// recordRun saves the entry to the journal in hakiDir. Debug runs and runs that stored nothing are left out, as
+// there is nothing to undo. Failing to journal a run shouldn't fail the run itself.
+func recordRun(hakiDir string, entry *JournalEntry, debug bool) {
+	if debug || entry.IsEmpty() {
+		return
+	}
+	if err := NewJournal(hakiDir).Record(entry); err != nil {
+		slog.Error("record run", slog.String("id", entry.ID), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/netr/haki/kindle"
+	"github.com/netr/haki/lib"
+)
+
+var ErrKindleFilesRequired = errors.New("a Kindle, --clippings or --vocab is required")
+
+func newKindleImportCommand(apiKey, outputDir string, config func(command string) CommandConfig, profiles []DeckProfile) *cli.Command {
+	return &cli.Command{
+		Name:      "kindle",
+		Usage:     "Import highlights from My Clippings.txt and looked up words from the Vocabulary Builder.",
+		ArgsUsage: "--clippings <My Clippings.txt> --vocab <vocab.db> " + generationArgsUsage + " [kindle]",
+		Flags:     append([]cli.Flag{newClippingsFlag(), newVocabDBFlag()}, generationFlags()...),
+		Action: actionFn(
+			NewKindleImportAction(
+				apiKey,
+				"kindle",
+				outputDir,
+				config("vocab"),
+				config("topic"),
+				profiles,
+				append([]string{"clippings", "vocab"}, generationFlagNames...),
+			)),
+	}
+}
+
+func newClippingsFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "clippings",
+		Value: "",
+		Usage: "path to My Clippings.txt, whose highlights are turned into topic cards",
+	}
+}
+
+func newVocabDBFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "vocab",
+		Value: "",
+		Usage: "path to the Vocabulary Builder's vocab.db, whose words are turned into vocabulary cards",
+	}
+}
+
+type KindleImportAction struct {
+	Action
+	outputDir   string
+	vocabConfig CommandConfig
+	topicConfig CommandConfig
+	profiles    []DeckProfile
+}
+
+func NewKindleImportAction(apiKey, name, outputDir string, vocabCfg, topicCfg CommandConfig, profiles []DeckProfile, flags []string) *KindleImportAction {
+	return &KindleImportAction{
+		Action: Action{
+			flags:  flags,
+			apiKey: apiKey,
+			name:   name,
+		},
+		outputDir:   outputDir,
+		vocabConfig: vocabCfg.WithDefaults("vocab"),
+		topicConfig: topicCfg.WithDefaults("topic"),
+		profiles:    profiles,
+	}
+}
+
+// TakesArgs makes the Kindle given after the flags part of Run's arguments.
+func (a KindleImportAction) TakesArgs() bool {
+	return true
+}
+
+func (a KindleImportAction) Run(args ...interface{}) error {
+	if len(args) < 2+len(generationFlagNames) {
+		return fmt.Errorf("action run: %w", ErrKindleFilesRequired)
+	}
+	clippingsPath, vocabPath := args[0].(string), args[1].(string)
+	gen, err := parseGenerationArgs(args[2 : 2+len(generationFlagNames)])
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+	// A mounted Kindle fills in whichever file wasn't given.
+	if rest := args[2+len(generationFlagNames):]; len(rest) > 0 {
+		device := rest[0].(string)
+		if p := filepath.Join(device, kindle.ClippingsPath); clippingsPath == "" && lib.FileExists(p) {
+			clippingsPath = p
+		}
+		if p := filepath.Join(device, kindle.VocabPath); vocabPath == "" && lib.FileExists(p) {
+			vocabPath = p
+		}
+	}
+	if clippingsPath == "" && vocabPath == "" {
+		return fmt.Errorf("action run: %w", ErrKindleFilesRequired)
+	}
+
+	run := NewJournalEntry(a.Name(), slices.DeleteFunc([]string{clippingsPath, vocabPath}, func(p string) bool { return p == "" })...)
+	defer recordRun(a.outputDir, run, gen.Debug)
+
+	imports := NewImportLog(a.outputDir)
+	if vocabPath != "" {
+		if err := a.importWords(vocabPath, gen, run, imports); err != nil {
+			return fmt.Errorf("import kindle: %w", err)
+		}
+	}
+	if clippingsPath != "" {
+		if err := a.importHighlights(clippingsPath, gen, run, imports); err != nil {
+			return fmt.Errorf("import kindle: %w", err)
+		}
+	}
+	return nil
+}
+
+// importWords runs every new looked up word through the vocab pipeline, with the sentence it was looked up in.
+func (a KindleImportAction) importWords(path string, gen generationArgs, run *JournalEntry, imports *ImportLog) error {
+	lookups, err := kindle.ReadVocab(path)
+	if err != nil {
+		return err
+	}
+	model := a.vocabConfig.model(gen.Overrides.Model)
+
+	for _, l := range lookups {
+		key := "word:" + l.ID
+		done, err := imports.Imported(a.Name(), key)
+		if err != nil {
+			return err
+		}
+		if done {
+			continue
+		}
+
+		word := l.Stem
+		if word == "" {
+			word = l.Word
+		}
+		opts := importOptions(a.apiKey, a.outputDir, "vocab", a.vocabConfig, a.profiles, l.Book, gen, run)
+		if l.Usage != "" {
+			opts.Context = fmt.Sprintf("\"%s\" (%s)", l.Usage, l.Book)
+		}
+		slog.Info("importing word", slog.String("word", word), slog.String("book", l.Book))
+		if err := runVocab(a.apiKey, word, model, a.outputDir, gen.Debug, opts); err != nil {
+			return err
+		}
+		if gen.Debug {
+			continue
+		}
+		if err := imports.Add(a.Name(), key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importHighlights runs the new highlights and notes of every book through the topic pipeline, one book at a time.
+func (a KindleImportAction) importHighlights(path string, gen generationArgs, run *JournalEntry, imports *ImportLog) error {
+	clippings, err := kindle.ReadClippings(path)
+	if err != nil {
+		return err
+	}
+
+	var batches []*highlightBatch
+	byBook := map[string]*highlightBatch{}
+	for _, c := range clippings {
+		key := "clipping:" + c.Key()
+		done, err := imports.Imported(a.Name(), key)
+		if err != nil {
+			return err
+		}
+		if done {
+			continue
+		}
+		b, ok := byBook[c.Title]
+		if !ok {
+			b = &highlightBatch{Title: c.Title, Author: c.Author}
+			byBook[c.Title] = b
+			batches = append(batches, b)
+		}
+		text := c.Text
+		if c.Kind == kindle.KindNote {
+			text = "Note: " + text
+		}
+		b.Texts = append(b.Texts, text)
+		b.Keys = append(b.Keys, key)
+	}
+
+	imp := highlightImporter{
+		apiKey:    a.apiKey,
+		outputDir: a.outputDir,
+		name:      a.Name(),
+		config:    a.topicConfig,
+		profiles:  a.profiles,
+		gen:       gen,
+		run:       run,
+		imports:   imports,
+	}
+	for _, b := range batches {
+		if err := imp.importBatch(*b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/netr/haki/ai"
+)
+
+var ErrNoDeckSuggested = errors.New("no deck suggested")
+
+// pickDeck lets the user choose between the AI's deck suggestions, or type another deck name.
+// An empty answer picks the first suggestion.
+func pickDeck(suggestions []ai.DeckSuggestion) (string, error) {
+	fmt.Println("Which deck should the cards go in?")
+	for i, s := range suggestions {
+		fmt.Printf("  %d. %s (%.0f%%)", i+1, s.Deck, s.Confidence*100)
+		if s.Rationale != "" {
+			fmt.Printf(" - %s", s.Rationale)
+		}
+		fmt.Println()
+	}
+
+	for {
+		answer, err := readLine(fmt.Sprintf("Choose 1-%d or type a deck name [1]: ", len(suggestions)))
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			return suggestions[0].Deck, nil
+		}
+		n, err := strconv.Atoi(answer)
+		if err != nil {
+			return answer, nil
+		}
+		if n >= 1 && n <= len(suggestions) {
+			return suggestions[n-1].Deck, nil
+		}
+		fmt.Printf("%d is not one of the suggestions.\n", n)
+	}
+}
@@ -8,41 +8,198 @@ import (
 	"regexp"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/netr/haki/ai"
 	"github.com/netr/haki/anki"
 	"github.com/netr/haki/lib"
+	"github.com/netr/haki/prompt"
 )
 
 type AnkiCardGeneratorPlugin interface {
+	Validate() error
 	GenerateAnkiCards(ctx context.Context, query string) ([]ai.AnkiCard, error)
+	ReviewAnkiCards(query, deckName string, cards []ai.AnkiCard) (string, []ai.AnkiCard, error)
 	StoreAnkiCards(deckName string, cards []ai.AnkiCard) error
 	ChooseDeck(ctx context.Context, query string) (string, error)
+	// GenerationTimeout is how long GenerateAnkiCards may take for the query.
+	GenerationTimeout(query string) time.Duration
 }
 
 type BasePlugin struct {
-	ankiClient anki.AnkiClienter
-	ankiAI     ai.AnkiController
-	deckName   string
-	ankiCards  []ai.AnkiCard
+	ankiClient     anki.AnkiClienter
+	ankiAI         ai.AnkiController
+	commandAI      ai.AnkiController
+	newCardCreator CardCreatorFunc
+	deckName       string
+	ankiCards      []ai.AnkiCard
+	run            *JournalEntry
+	onDuplicate    DuplicatePolicy
+	commandConfig  CommandConfig
+	config         CommandConfig
+	profiles       []DeckProfile
+	overrides      Overrides
+	prompts        *prompt.Library
+	promptID       string
+	routes         *RouteMemory
+	critiques      map[int]ai.Critique
+	origin         string
+	context        string
+	replace        []float64
+	unused         []float64
+	noteIDs        []float64
+	matched        []float64
+	sample         *deckSample
+	routedQuery    string
+	suggestedDeck  string
+}
+
+// CardCreatorFunc creates a card creator for the given model.
+type CardCreatorFunc func(model string) (ai.AnkiController, error)
+
+// Overrides are settings given as command line flags. They take precedence over the config and deck profiles.
+type Overrides struct {
+	NoteType   string
+	PromptName string
+	Model      string
+	// Count is the exact number of cards to create and MaxCards the most. Zero leaves them unset.
+	Count    int
+	MaxCards int
+	Level    prompt.Level
+	Style    prompt.Style
+	// Deck skips deck routing. AllowNewDeck lets the AI create decks without asking.
+	Deck         string
+	AllowNewDeck bool
+	// Review is set by --review and --no-review. Empty uses the config.
+	Review ReviewPolicy
+	Critic CriticMode
+	// Grounded makes every card cite the input, see groundCards.
+	Grounded bool
+}
+
+// PluginOptions are the per-run settings shared by all plugins.
+type PluginOptions struct {
+	// Run records the notes stored by the plugin. It may be nil.
+	Run *JournalEntry
+	// OnDuplicate decides what happens when a note already exists.
+	OnDuplicate DuplicatePolicy
+	// Config is the command's config, and the profile in Profiles matching the chosen deck adjusts it.
+	Config   CommandConfig
+	Profiles []DeckProfile
+	// Overrides are the command line flags, which win over both.
+	Overrides Overrides
+	// Prompts is where prompt templates are loaded from.
+	Prompts *prompt.Library
+	// NewCardCreator creates a card creator for a deck profile that uses a different model. It may be nil.
+	NewCardCreator CardCreatorFunc
+	// Routes remembers deck decisions between runs. It may be nil.
+	Routes *RouteMemory
+	// Origin is where the input came from, such as a file and heading. Cards cite it as their source.
+	Origin string
+	// Context is sent to the AI along with the input, such as the sentence a word was looked up in.
+	Context string
+	// Replace are notes made from an earlier version of the input. Stored cards overwrite them in order.
+	Replace []float64
+}
+
+// newPluginOptions returns the options a command's plugins start from: its config and deck profiles, the
+// generation flags, the user's prompt templates and the decks remembered for the command.
+func newPluginOptions(apiKey, outputDir, command string, cfg CommandConfig, profiles []DeckProfile, gen generationArgs, run *JournalEntry) PluginOptions {
+	return PluginOptions{
+		Run:            run,
+		OnDuplicate:    gen.OnDuplicate,
+		Config:         cfg,
+		Profiles:       profiles,
+		Overrides:      gen.Overrides,
+		Prompts:        prompt.NewLibrary(filepath.Join(outputDir, "prompts")),
+		NewCardCreator: newCardCreatorFunc(apiKey),
+		Routes:         NewRouteMemory(outputDir, command),
+	}
+}
+
+func NewBasePlugin(c ai.AnkiController, opts PluginOptions) *BasePlugin {
+	return &BasePlugin{
+		ankiClient:     newAnkiClient(),
+		ankiAI:         c,
+		commandAI:      c,
+		newCardCreator: opts.NewCardCreator,
+		run:            opts.Run,
+		onDuplicate:    opts.OnDuplicate,
+		commandConfig:  opts.Config,
+		config:         opts.Config,
+		profiles:       opts.Profiles,
+		overrides:      opts.Overrides,
+		prompts:        opts.Prompts,
+		routes:         opts.Routes,
+		origin:         opts.Origin,
+		context:        opts.Context,
+		replace:        slices.Clone(opts.Replace),
+	}
+}
+
+// noteType returns the note type cards are stored as and how card attributes map onto its fields.
+func (t *BasePlugin) noteType() (string, FieldMapping) {
+	return t.config.noteType(t.overrides.NoteType)
+}
+
+// Validate checks the plugin's field mapping against the fields of its note type, so a bad config
+// is caught before anything is generated.
+func (t *BasePlugin) Validate() error {
+	noteType, fields := t.noteType()
+	fieldNames, err := t.ankiClient.ModelNames().FieldNames(noteType)
+	if err != nil {
+		return fmt.Errorf("validate note type (%s): %w", noteType, err)
+	}
+	if err := fields.Validate(fieldNames); err != nil {
+		return fmt.Errorf("validate note type (%s): %w", noteType, err)
+	}
+	return nil
 }
 
-func NewBasePlugin(c ai.AnkiController) *BasePlugin {
-	return &BasePlugin{
-		ankiClient: anki.NewClient(lib.GetEnv("ANKI_CONNECT_URL", "http://localhost:8765")),
-		ankiAI:     c,
+// switchModel replaces the card creator when the config asks for a different model than the one in use,
+// unless the model was given on the command line. A config without a model goes back to the command's creator.
+func (t *BasePlugin) switchModel() error {
+	model := t.config.Model
+	if t.overrides.Model != "" {
+		return nil
+	}
+	if model == "" {
+		if t.ankiAI != t.commandAI {
+			t.ankiAI = t.commandAI
+			if t.run != nil {
+				t.run.Model = t.ankiAI.ModelName().String()
+			}
+		}
+		return nil
+	}
+	if t.newCardCreator == nil || model == t.ankiAI.ModelName().String() {
+		return nil
+	}
+
+	c, err := t.newCardCreator(model)
+	if err != nil {
+		return fmt.Errorf("switch model (%s): %w", model, err)
+	}
+	t.ankiAI = c
+	if t.run != nil {
+		t.run.Model = model
 	}
+	return nil
 }
 
-func (t *BasePlugin) getFilteredDeckNames(filter ...string) ([]string, error) {
+// getRootDeckNames returns the decks below the command's deck roots, offering to create a missing root.
+func (t *BasePlugin) getRootDeckNames() ([]string, error) {
 	deckNames, err := t.listBaseDeckNames()
 	if err != nil {
-		return nil, fmt.Errorf("get filtered deck names: %w", err)
+		return nil, fmt.Errorf("get root deck names: %w", err)
 	}
 
-	decks, err := t.filterDecksByName(deckNames, filter...)
-	if err != nil {
-		return nil, fmt.Errorf("get filtered deck names: %w", err)
+	decks := filterDecksByRoots(deckNames, t.config.DeckRoots)
+	if len(decks) == 0 {
+		decks, err = t.createDeckRoots()
+		if err != nil {
+			return nil, fmt.Errorf("get root deck names: %w", err)
+		}
 	}
 	return decks, nil
 }
@@ -57,58 +214,350 @@ func (t *BasePlugin) listBaseDeckNames() ([]string, error) {
 	return anki.FilterDecksByHierarchy(deckNames), nil
 }
 
-func (t *BasePlugin) filterDecksByName(deckNames []string, filter ...string) ([]string, error) {
-	if len(filter) == 0 {
-		return deckNames, nil
+// filterDecksByRoots returns the decks that fall under any of the roots. A root containing glob characters
+// is matched against the full deck name, otherwise it matches the deck with that exact name and its subdecks.
+// No roots means every deck.
+func filterDecksByRoots(deckNames []string, roots []string) []string {
+	if len(roots) == 0 {
+		return deckNames
 	}
 
-	// only use deck names that have [filter] in them
 	var decks []string
 	for _, d := range deckNames {
-		if strings.Contains(d, filter[0]) {
+		if slices.ContainsFunc(roots, func(root string) bool { return inDeckRoot(root, d) }) {
 			decks = append(decks, d)
 		}
 	}
-	return decks, nil
+	return decks
+}
+
+func inDeckRoot(root, deckName string) bool {
+	if isDeckGlob(root) {
+		return matchDeckGlob(root, deckName)
+	}
+	return deckName == root || strings.HasPrefix(deckName, root+"::")
 }
 
-func (t *BasePlugin) generateAnkiCards(ctx context.Context, query string, prompt string) ([]ai.AnkiCard, error) {
+func isDeckGlob(root string) bool {
+	return strings.ContainsAny(root, "*?[")
+}
+
+// createDeckRoots offers to create the command's deck roots when none of them exist yet.
+// Glob roots can't be created, so they're left out.
+func (t *BasePlugin) createDeckRoots() ([]string, error) {
+	var missing []string
+	for _, root := range t.config.DeckRoots {
+		if !isDeckGlob(root) {
+			missing = append(missing, root)
+		}
+	}
+	if len(missing) == 0 || !isInteractive() {
+		return nil, fmt.Errorf("%s: %w", strings.Join(t.config.DeckRoots, ", "), ErrNoDecksInRoots)
+	}
+
+	ok, err := confirm(fmt.Sprintf("No decks found under %s. Create %s?", strings.Join(t.config.DeckRoots, ", "), strings.Join(missing, ", ")))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", strings.Join(t.config.DeckRoots, ", "), ErrNoDecksInRoots)
+	}
+	for _, root := range missing {
+		if err := t.ankiClient.DeckNames().Create(root); err != nil {
+			return nil, fmt.Errorf("create deck root (%s): %w", root, err)
+		}
+		slog.Info("deck root created", slog.String("deck", root))
+	}
+	return missing, nil
+}
+
+func (t *BasePlugin) generateAnkiCards(ctx context.Context, query string) ([]ai.AnkiCard, error) {
+	count, maxCards := t.cardLimits()
+	query = t.generationQuery(query)
+
+	var cards []ai.AnkiCard
+	var err error
+	if chunks := t.chunkInput(query); len(chunks) > 1 {
+		cards, err = t.generateChunkedCards(ctx, chunks, count)
+	} else {
+		cards, err = t.generateCards(ctx, query, count)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("generate anki cards: %w", err)
+	}
+	cards = limitCards(cards, count, maxCards)
+
+	cards, err = t.critiqueCards(ctx, cards)
+	if err != nil {
+		return nil, fmt.Errorf("generate anki cards: %w", err)
+	}
+	if t.grounded() {
+		var kept []int
+		if cards, kept, err = t.groundCards(query, cards); err != nil {
+			return nil, fmt.Errorf("generate anki cards: %w", err)
+		}
+		t.keepCritiques(kept)
+	}
+	// Splits can add cards, but never past the maximum.
+	return t.citeCards(limitCards(cards, 0, maxCards)), nil
+}
+
+// generationQuery adds the plugin's context to the query cards are generated for.
+func (t *BasePlugin) generationQuery(query string) string {
+	if t.context != "" {
+		query += "\n\nContext: " + t.context
+	}
+	return query
+}
+
+// generateCards generates the cards for a single request, asking again when there are fewer than count.
+func (t *BasePlugin) generateCards(ctx context.Context, query string, count int) ([]ai.AnkiCard, error) {
+	systemPrompt, err := t.renderPrompt(count)
+	if err != nil {
+		return nil, err
+	}
+
 	cards, err := t.ankiAI.GenerateAnkiCards(
 		ctx,
 		t.deckName,
 		query,
-		prompt,
+		systemPrompt,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("generate anki cards: %w", err)
+		return nil, err
+	}
+
+	if count > 0 {
+		return t.topUpCards(ctx, query, cards, count)
 	}
 	return cards, nil
 }
 
+// renderPrompt loads the prompt template for the chosen deck and fills it in, asking for count cards.
+func (t *BasePlugin) renderPrompt(count int) (string, error) {
+	prompts := t.prompts
+	if prompts == nil {
+		prompts = prompt.NewLibrary("")
+	}
+	tmpl, err := prompts.Load(t.config.promptSelector(t.overrides.PromptName).Name(t.deckName))
+	if err != nil {
+		return "", fmt.Errorf("render prompt: %w", err)
+	}
+
+	level, style, err := t.audience()
+	if err != nil {
+		return "", fmt.Errorf("render prompt: %w", err)
+	}
+	_, maxCards := t.cardLimits()
+
+	sample := t.promptSample()
+	text, err := tmpl.Render(prompt.Data{
+		DeckName: t.deckName,
+		Tags:     sample.tags,
+		Language: t.config.Language,
+		Count:    count,
+		MaxCards: maxCards,
+		Level:    level,
+		Style:    style,
+		Grounded: t.grounded(),
+		Examples: sample.examples,
+	})
+	if err != nil {
+		return "", fmt.Errorf("render prompt: %w", err)
+	}
+
+	t.promptID = tmpl.ID()
+	if t.run != nil {
+		t.run.Prompt = t.promptID
+	}
+	slog.Info("prompt rendered", slog.String("prompt", t.promptID), slog.String("deck", t.deckName))
+	return text, nil
+}
+
+// promptTag marks a note with the prompt template and version it was generated with.
+func (t *BasePlugin) promptTag() string {
+	if t.promptID == "" {
+		return ""
+	}
+	return "haki::prompt::" + anki.NormalizeTag(strings.ReplaceAll(t.promptID, "@", "::v"))
+}
+
 func (t *BasePlugin) chooseDeck(ctx context.Context, query string, decks []string, createIfNotExists bool) (string, error) {
-	deckName, err := t.ankiAI.ChooseDeck(ctx, decks, fmt.Sprintf("Which deck should I use for the topic: %s", query))
+	suggestions, err := t.ankiAI.ChooseDeck(ctx, decks, fmt.Sprintf("Which deck should I use for the topic: %s", query))
 	if err != nil {
 		return "", fmt.Errorf("choose deck: %w", err)
 	}
+	if len(suggestions) == 0 {
+		return "", fmt.Errorf("choose deck: %w", ErrNoDeckSuggested)
+	}
+
+	top := suggestions[0]
+	slog.Info("deck suggested",
+		slog.String("deck", top.Deck),
+		slog.Float64("confidence", top.Confidence),
+		slog.String("rationale", top.Rationale),
+	)
+	deckName := top.Deck
+	if top.Confidence < t.config.DeckConfidence {
+		if !isInteractive() {
+			if t.config.FallbackDeck != "" {
+				return t.useDeck(t.config.FallbackDeck, "fallback")
+			}
+			slog.Warn("low confidence deck suggestion used", slog.String("deck", deckName))
+			return t.confirmDeck(deckName, decks, createIfNotExists)
+		}
+		if deckName, err = pickDeck(suggestions); err != nil {
+			return "", fmt.Errorf("choose deck: %w", err)
+		}
+		if !slices.ContainsFunc(suggestions, func(s ai.DeckSuggestion) bool { return s.Deck == deckName }) {
+			// The user typed the deck name themselves, so there's nothing to confirm.
+			t.routeQuery(query, top.Deck)
+			return t.useDeck(deckName, "user")
+		}
+	}
+
+	deckName, err = t.confirmDeck(deckName, decks, createIfNotExists)
+	if err != nil {
+		return "", err
+	}
+	t.routeQuery(query, top.Deck)
+	return deckName, nil
+}
 
-	if createIfNotExists {
-		if !slices.Contains(decks, deckName) {
+// confirmDeck uses a deck chosen from the AI's suggestions, creating it once the user agrees if it's new.
+func (t *BasePlugin) confirmDeck(deckName string, decks []string, createIfNotExists bool) (string, error) {
+	if !slices.Contains(decks, deckName) {
+		// The model may invent a deck, which is only created once the user agrees.
+		if err := t.confirmNewDeck(deckName); err != nil {
+			return "", fmt.Errorf("choose deck (%s): %w", deckName, err)
+		}
+		if createIfNotExists {
 			if err := t.ankiClient.DeckNames().Create(deckName); err != nil {
 				return "", fmt.Errorf("choose deck (%s): %w", deckName, err)
 			}
 		}
 	}
 
-	t.deckName = deckName
+	if err := t.setDeck(deckName); err != nil {
+		return "", fmt.Errorf("choose deck (%s): %w", deckName, err)
+	}
+	return deckName, nil
+}
+
+// confirmNewDeck asks the user before using a deck the AI made up, unless new decks are allowed.
+func (t *BasePlugin) confirmNewDeck(deckName string) error {
+	if t.overrides.AllowNewDeck {
+		return nil
+	}
+	if !isInteractive() {
+		return fmt.Errorf("%s: %w", deckName, ErrNewDeckUnconfirmed)
+	}
+	ok, err := confirm(fmt.Sprintf("The AI suggested a new deck %q. Create it?", deckName))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNewDeckDeclined
+	}
+	return nil
+}
+
+// useDeck uses a deck picked by the user or a routing rule, creating it if it doesn't exist yet.
+func (t *BasePlugin) useDeck(deckName, reason string) (string, error) {
+	deckNames, err := t.ankiClient.DeckNames().GetNames()
+	if err != nil {
+		return "", fmt.Errorf("use deck (%s): %w", deckName, err)
+	}
+	if !slices.Contains(deckNames, deckName) {
+		if err := t.ankiClient.DeckNames().Create(deckName); err != nil {
+			return "", fmt.Errorf("use deck (%s): %w", deckName, err)
+		}
+	}
+
+	slog.Info("deck chosen", slog.String("deck", deckName), slog.String("by", reason))
+	if err := t.setDeck(deckName); err != nil {
+		return "", fmt.Errorf("use deck (%s): %w", deckName, err)
+	}
 	return deckName, nil
 }
 
-func (t *BasePlugin) chooseFilteredDeck(ctx context.Context, deckFilter string, query string) (string, error) {
+// recallDeck returns the route a similar query went to before, if its deck still exists. The route is only
+// counted as used once the cards are stored, see rememberDeck.
+// The memory only saves an AI call, so failing to read it is logged rather than returned.
+func (t *BasePlugin) recallDeck(query string) *RouteEntry {
+	if t.routes == nil {
+		return nil
+	}
+	route, err := t.routes.Recall(query)
+	if err != nil {
+		slog.Error("recall deck", slog.String("error", err.Error()))
+		return nil
+	}
+	if route == nil {
+		return nil
+	}
+
+	deckNames, err := t.ankiClient.DeckNames().GetNames()
+	if err != nil || !slices.Contains(deckNames, route.Deck) {
+		return nil
+	}
+	slog.Info("deck recalled", slog.String("deck", route.Deck), slog.String("query", route.Query))
+	return route
+}
+
+// routeQuery notes the query a deck was chosen for, so the deck its cards are stored in can be remembered.
+// suggested is the deck the AI, a rule or the memory picked, and empty when the deck was given with --deck.
+func (t *BasePlugin) routeQuery(query, suggested string) {
+	t.routedQuery, t.suggestedDeck = query, suggested
+}
+
+// rememberDeck records that the cards of the routed query were stored in deckName, which is the deck the user
+// moved them to when they changed it during review. Runs that store nothing teach the router nothing.
+func (t *BasePlugin) rememberDeck(deckName string) {
+	if t.routes == nil || t.routedQuery == "" {
+		return
+	}
+	suggested := t.suggestedDeck
+	if suggested == deckName {
+		suggested = ""
+	}
+	if err := t.routes.Remember(t.routedQuery, deckName, suggested); err != nil {
+		slog.Error("remember deck", slog.String("error", err.Error()))
+	}
+	t.routedQuery, t.suggestedDeck = "", ""
+}
+
+// setDeck makes deckName the deck cards are generated for and applies its profile.
+func (t *BasePlugin) setDeck(deckName string) error {
+	t.deckName = deckName
+	t.sample = nil
+	return t.applyProfile(deckName)
+}
+
+func (t *BasePlugin) chooseFilteredDeck(ctx context.Context, query string) (string, error) {
 	if query == "" {
 		return "", ErrQueryRequired
 	}
 
-	decks, err := t.getFilteredDeckNames(deckFilter)
+	if t.overrides.Deck != "" {
+		t.routeQuery(query, "")
+		return t.useDeck(t.overrides.Deck, "flag")
+	}
+	routed, err := routeDeck(t.config.Routes, query)
+	if err != nil {
+		return "", fmt.Errorf("choose filtered deck: %w", err)
+	}
+	if routed != "" {
+		t.routeQuery(query, routed)
+		return t.useDeck(routed, "route")
+	}
+	if route := t.recallDeck(query); route != nil {
+		// Storing the cards updates the recalled route, so moving them to another deck corrects it.
+		t.routeQuery(route.Query, route.Deck)
+		return t.useDeck(route.Deck, "memory")
+	}
+
+	decks, err := t.getRootDeckNames()
 	if err != nil {
 		return "", err
 	}
@@ -140,26 +589,27 @@ func PrintCards(ac []ai.AnkiCard, padding bool) {
 // ==================================================
 
 var (
-	ErrQueryRequired = fmt.Errorf("query is required")
+	ErrQueryRequired  = fmt.Errorf("query is required")
+	ErrNoDecksInRoots = fmt.Errorf("no decks found under the deck roots")
 )
 
 type TopicPlugin struct {
 	*BasePlugin
 }
 
-func newTopicPlugin(c ai.AnkiController) AnkiCardGeneratorPlugin {
+func newTopicPlugin(c ai.AnkiController, opts PluginOptions) AnkiCardGeneratorPlugin {
 	t := &TopicPlugin{
-		BasePlugin: NewBasePlugin(c),
+		BasePlugin: NewBasePlugin(c, opts),
 	}
 	return t
 }
 
 func (t *TopicPlugin) ChooseDeck(ctx context.Context, query string) (string, error) {
-	return t.BasePlugin.chooseFilteredDeck(ctx, "Haki", query)
+	return t.BasePlugin.chooseFilteredDeck(ctx, query)
 }
 
 func (t *TopicPlugin) GenerateAnkiCards(ctx context.Context, query string) ([]ai.AnkiCard, error) {
-	cards, err := t.generateAnkiCards(ctx, query, generateAnkiCardPrompt())
+	cards, err := t.generateAnkiCards(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("topic: %w", err)
 	}
@@ -169,25 +619,24 @@ func (t *TopicPlugin) GenerateAnkiCards(ctx context.Context, query string) ([]ai
 }
 
 func (t *TopicPlugin) StoreAnkiCards(deckName string, cards []ai.AnkiCard) error {
-	const modelName = "Basic"
 	for _, c := range cards {
-		data := map[string]interface{}{
-			"Front": c.Front,
-			"Back":  formatBack(c.Back),
+		noteType, fields := t.noteType()
+		note, err := buildCardNote(deckName, c, noteType, fields)
+		if err != nil {
+			slog.Error("failed building note",
+				slog.String("deck", deckName),
+				slog.String("kind", string(c.Kind)),
+				slog.String("error", err.Error()),
+			)
+			continue
 		}
 
-		note := anki.NewNoteBuilder(deckName, modelName, data).Build()
-
-		id, err := t.ankiClient.Notes().Add(note)
-		if err != nil {
+		if err := t.storeNote(note.Build()); err != nil {
 			return fmt.Errorf("topic: %w", err)
 		}
-		slog.Info(
-			"note added",
-			slog.String("deck", deckName),
-			slog.String("model", modelName),
-			slog.String("id", fmt.Sprintf("%.f", id)),
-		)
+	}
+	if len(cards) > 0 {
+		t.rememberDeck(deckName)
 	}
 	return nil
 }
@@ -206,9 +655,9 @@ type VocabPlugin struct {
 	outputDir       string
 }
 
-func newVocabPlugin(cardCreator ai.AnkiController, ttsService ai.TTS, imageGenService ai.ImageGen, outputDir string) AnkiCardGeneratorPlugin {
+func newVocabPlugin(cardCreator ai.AnkiController, ttsService ai.TTS, imageGenService ai.ImageGen, outputDir string, opts PluginOptions) AnkiCardGeneratorPlugin {
 	e := &VocabPlugin{
-		BasePlugin:      NewBasePlugin(cardCreator),
+		BasePlugin:      NewBasePlugin(cardCreator, opts),
 		ttsService:      ttsService,
 		imageGenService: imageGenService,
 		outputDir:       outputDir,
@@ -217,55 +666,74 @@ func newVocabPlugin(cardCreator ai.AnkiController, ttsService ai.TTS, imageGenSe
 }
 
 func (v *VocabPlugin) ChooseDeck(ctx context.Context, query string) (string, error) {
-	return v.BasePlugin.chooseFilteredDeck(ctx, "Vocabulary", query)
+	return v.BasePlugin.chooseFilteredDeck(ctx, query)
 }
 
 func (v *VocabPlugin) GenerateAnkiCards(ctx context.Context, query string) ([]ai.AnkiCard, error) {
-	cards, err := v.generateAnkiCards(ctx, query, generateAnkiCardPrompt())
+	cards, err := v.generateAnkiCards(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("vocab: %w", err)
 	}
 	slog.Info("anki card(s) created", slog.Int("count", len(v.ankiCards)))
 
-	if _, err := v.generateTTS(ctx, query); err != nil {
-		slog.Error("vocab: create tts", slog.String("error", err.Error()))
-	} else {
-		slog.Info("tts created", slog.String("file_path", v.ttsFilePath))
+	if v.config.ttsEnabled() {
+		if _, err := v.generateTTS(ctx, query); err != nil {
+			slog.Error("vocab: create tts", slog.String("error", err.Error()))
+		} else {
+			slog.Info("tts created", slog.String("file_path", v.ttsFilePath))
+		}
 	}
 
-	if _, err := v.generateImage(ctx, query); err != nil {
-		slog.Error("vocab: create image", slog.String("error", err.Error()))
-	} else {
-		slog.Info("image created", slog.String("file_path", v.imageFilePath))
+	if v.config.imageEnabled() {
+		if _, err := v.generateImage(ctx, query); err != nil {
+			slog.Error("vocab: create image", slog.String("error", err.Error()))
+		} else {
+			slog.Info("image created", slog.String("file_path", v.imageFilePath))
+		}
 	}
 
 	v.word = query
 	return cards, nil
 }
 
+// vocabModelName is the default note type for vocabulary cards.
+const vocabModelName = "VocabularyWithAudio"
+
 func (v *VocabPlugin) StoreAnkiCards(deckName string, cards []ai.AnkiCard) error {
-	const modelName = "VocabularyWithAudio"
 	for _, c := range cards {
-		note, err := v.buildNote(modelName, c)
+		if c.IsCloze() {
+			// Cloze text can't be shown on the vocabulary model, so it is stored as a plain cloze note instead.
+			note, err := buildCardNote(deckName, c, anki.ModelCloze, clozeFieldMapping)
+			if err != nil {
+				slog.Error("failed building note",
+					slog.String("deck", deckName),
+					slog.String("model", anki.ModelCloze),
+					slog.String("error", err.Error()),
+				)
+				continue
+			}
+			if err := v.storeNote(note.Build()); err != nil {
+				return fmt.Errorf("vocab: %w", err)
+			}
+			continue
+		}
+
+		note, err := v.buildNote(c)
 		if err != nil {
 			slog.Error("failed building note",
 				slog.String("deck", deckName),
-				slog.String("model", modelName),
+				slog.String("model", note.ModelName),
 				slog.String("error", err.Error()),
 			)
 			continue
 		}
 
-		id, err := v.ankiClient.Notes().Add(note)
-		if err != nil {
+		if err := v.storeNote(note, v.mediaFileNames()...); err != nil {
 			return fmt.Errorf("vocab: %w", err)
 		}
-		slog.Info(
-			"note added",
-			slog.String("deck", deckName),
-			slog.String("model", modelName),
-			slog.String("id", fmt.Sprintf("%.f", id)),
-		)
+	}
+	if len(cards) > 0 {
+		v.rememberDeck(deckName)
 	}
 	return nil
 }
@@ -278,6 +746,18 @@ func (v *VocabPlugin) hasTTS() bool {
 	return strings.TrimSpace(v.ttsFilePath) != ""
 }
 
+// mediaFileNames returns the names the generated media is stored under in Anki.
+func (v *VocabPlugin) mediaFileNames() []string {
+	var names []string
+	if v.hasTTS() {
+		names = append(names, makeTTSFileName(v.word))
+	}
+	if v.hasImage() {
+		names = append(names, makeImageFileName(v.word))
+	}
+	return names
+}
+
 func (v *VocabPlugin) generateTTS(ctx context.Context, query string) (string, error) {
 	mp3Bytes, err := v.ttsService.GenerateMP3(ctx, query)
 	if err != nil {
@@ -319,39 +799,31 @@ func makeImageFilePath(outputDir, word string) (string, error) {
 	return filepath.Abs(fmt.Sprintf("%s/data/%s.webp", outputDir, word))
 }
 
-func (v *VocabPlugin) buildNote(modelName string, c ai.AnkiCard) (anki.Note, error) {
-	data := map[string]interface{}{
-		"Question":   c.Front,
-		"Definition": formatBack(c.Back),
-	}
-
-	note := anki.NewNoteBuilder(v.deckName, modelName, data)
+func (v *VocabPlugin) buildNote(c ai.AnkiCard) (anki.Note, error) {
+	noteType, fields := v.noteType()
+	note := anki.NewNoteBuilder(v.deckName, noteType, fields.Fields(c)).
+		WithTags(cardTags(c)...)
 
+	// The media tags are written into their mapped fields directly.
 	if v.hasTTS() {
-		note.
-			SetField(
-				"Audio",
-				createAudioTag(makeTTSFileName(v.word)),
-			).
-			WithAudio(
-				v.ttsFilePath,
-				makeTTSFileName(v.word),
-				"Front",
-			)
+		if fields.Audio != "" {
+			note.SetField(fields.Audio, createAudioTag(makeTTSFileName(v.word)))
+		}
+		note.WithAudio(
+			v.ttsFilePath,
+			makeTTSFileName(v.word),
+		)
 	}
 
 	if v.hasImage() {
-		note.
-			SetField(
-				"Picture",
-				createImageTag(makeImageFileName(v.word)),
-			).
-			WithPicture(
-				"",
-				v.imageFilePath,
-				makeImageFileName(v.word),
-				"Front",
-			)
+		if fields.Image != "" {
+			note.SetField(fields.Image, createImageTag(makeImageFileName(v.word)))
+		}
+		note.WithPicture(
+			"",
+			v.imageFilePath,
+			makeImageFileName(v.word),
+		)
 	}
 
 	return note.Build(), nil
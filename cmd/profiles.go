@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"log/slog"
+	"path"
+	"slices"
+)
+
+// DeckProfile adjusts how cards are generated for the decks it matches. Any setting left empty
+// keeps the command's value, and tags are added to the command's tags.
+type DeckProfile struct {
+	// Name identifies the profile in logs.
+	Name string `json:"name"`
+	// Decks are glob patterns matched against the full deck name, e.g. "Haki::Programming*".
+	Decks []string `json:"decks"`
+	CommandConfig
+}
+
+// Matches reports whether the profile applies to the deck.
+func (p DeckProfile) Matches(deckName string) bool {
+	for _, pattern := range p.Decks {
+		if matchDeckGlob(pattern, deckName) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDeckGlob matches a deck name against a glob pattern. `*` matches across `::` separators,
+// so "Haki::*" matches every deck below Haki. Malformed patterns never match.
+func matchDeckGlob(pattern, deckName string) bool {
+	ok, err := path.Match(pattern, deckName)
+	return err == nil && ok
+}
+
+// findProfile returns the first profile matching the deck, or nil if none match.
+func findProfile(profiles []DeckProfile, deckName string) *DeckProfile {
+	for i := range profiles {
+		if profiles[i].Matches(deckName) {
+			return &profiles[i]
+		}
+	}
+	return nil
+}
+
+// withProfile layers a deck profile on top of the command config.
+func (c CommandConfig) withProfile(p DeckProfile) CommandConfig {
+	if p.NoteType != "" {
+		c.NoteType, c.Fields = c.noteType(p.NoteType)
+		if p.Fields.Front != "" && p.Fields.Back != "" {
+			c.Fields = p.Fields
+		}
+	}
+	if p.Prompt != "" {
+		c.Prompt = p.Prompt
+	}
+	if p.Model != "" {
+		c.Model = p.Model
+	}
+	if p.Language != "" {
+		c.Language = p.Language
+	}
+	if p.ExampleTokens != 0 {
+		c.ExampleTokens = p.ExampleTokens
+	}
+	if p.MaxCards != 0 {
+		c.MaxCards = p.MaxCards
+	}
+	if p.Level != "" {
+		c.Level = p.Level
+	}
+	if p.Style != "" {
+		c.Style = p.Style
+	}
+	if p.Grounded {
+		c.Grounded = true
+	}
+	if p.UnsupportedCards != "" {
+		c.UnsupportedCards = p.UnsupportedCards
+	}
+	if p.TTS != nil {
+		c.TTS = p.TTS
+	}
+	if p.Image != nil {
+		c.Image = p.Image
+	}
+	c.Tags = append(slices.Clone(c.Tags), p.Tags...)
+	return c
+}
+
+// applyProfile switches the plugin to the settings of the profile matching the deck, or back to the command's
+// settings when no profile matches. Either can change the note type, so it is validated again.
+func (t *BasePlugin) applyProfile(deckName string) error {
+	prevNoteType, _ := t.noteType()
+	if p := findProfile(t.profiles, deckName); p != nil {
+		t.config = t.commandConfig.withProfile(*p)
+		slog.Info("deck profile applied", slog.String("profile", p.Name), slog.String("deck", deckName))
+	} else {
+		t.config = t.commandConfig
+	}
+
+	if noteType, _ := t.noteType(); noteType != prevNoteType {
+		if err := t.Validate(); err != nil {
+			return err
+		}
+	}
+	return t.switchModel()
+}
@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/netr/haki/ai"
+)
+
+var (
+	ErrInvalidReviewPolicy = errors.New("invalid review policy, expected auto, always or never")
+	ErrNoCardGenerated     = errors.New("no card generated")
+	ErrReviewStdinItems    = errors.New("can't review cards when stdin is read for items, pass --input <file> or --review never")
+)
+
+// aiTimeout bounds a single AI call made while the user is reviewing cards.
+const aiTimeout = 60 * time.Second
+
+// ReviewPolicy decides when generated cards are reviewed before they're stored.
+type ReviewPolicy string
+
+const (
+	// ReviewAuto reviews cards when haki runs in a terminal, which is the default.
+	ReviewAuto ReviewPolicy = "auto"
+	// ReviewAlways reviews cards on every run.
+	ReviewAlways ReviewPolicy = "always"
+	// ReviewNever stores cards without asking.
+	ReviewNever ReviewPolicy = "never"
+)
+
+func ParseReviewPolicy(s string) (ReviewPolicy, error) {
+	switch p := ReviewPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case ReviewAuto, ReviewAlways, ReviewNever:
+		return p, nil
+	case "":
+		return ReviewAuto, nil
+	default:
+		return "", fmt.Errorf("%s: %w", s, ErrInvalidReviewPolicy)
+	}
+}
+
+// reviewPolicyFlags turns the --review and --no-review flags into a policy. Neither leaves it to the config.
+func reviewPolicyFlags(review, noReview string) ReviewPolicy {
+	switch {
+	case noReview == "true":
+		return ReviewNever
+	case review == "true":
+		return ReviewAlways
+	default:
+		return ""
+	}
+}
+
+// reviewEnabled reports whether cards should be reviewed, with the flags taking precedence over the config.
+func (t *BasePlugin) reviewEnabled() (bool, error) {
+	policy := t.overrides.Review
+	if policy == "" {
+		var err error
+		if policy, err = ParseReviewPolicy(t.config.Review); err != nil {
+			return false, fmt.Errorf("config: %w", err)
+		}
+	}
+
+	switch policy {
+	case ReviewAlways:
+		if stdinItems {
+			return false, ErrReviewStdinItems
+		}
+		return true, nil
+	case ReviewNever:
+		return false, nil
+	default:
+		return isTerminal(os.Stdout) && isInteractive(), nil
+	}
+}
+
+// ReviewAnkiCards lets the user accept, reject, edit or regenerate each card and move them to another deck.
+// It returns the deck to store the cards in and the accepted cards. Without review, everything is accepted.
+func (t *BasePlugin) ReviewAnkiCards(query, deckName string, cards []ai.AnkiCard) (string, []ai.AnkiCard, error) {
+	enabled, err := t.reviewEnabled()
+	if err != nil {
+		return "", nil, fmt.Errorf("review: %w", err)
+	}
+	if !enabled || len(cards) == 0 {
+		return deckName, cards, nil
+	}
+
+	cards, revised, err := t.refineCards(cards)
+	if err != nil {
+		return "", nil, fmt.Errorf("review: %w", err)
+	}
+	// Critiques follow their card by position, through edits and regenerations. A revised set has
+	// different cards, so the critiques no longer apply.
+	critiques := make([]*ai.Critique, len(cards))
+	if !revised {
+		for i, c := range t.critiques {
+			if i >= 0 && i < len(critiques) {
+				critiques[i] = &c
+			}
+		}
+	}
+
+	var accepted []ai.AnkiCard
+	for i := 0; i < len(cards); {
+		c := cards[i]
+		fmt.Printf("\nCard %d/%d in %s%s%s\n", i+1, len(cards), colors.Purple, t.deckName, colors.Reset)
+		fmt.Print(colors.BeautifyCard(c))
+		question := "[a]ccept, [r]eject, [e]dit, re[g]enerate, change [d]eck, accept a[l]l, [q]uit: "
+		critique := critiques[i]
+		critiqued := critique != nil
+		if critiqued {
+			printCritique(*critique)
+			if critique.NeedsRewrite() {
+				question = "use [s]uggestion, " + question
+			}
+		}
+
+		answer, err := readLine(question)
+		if err != nil {
+			return "", nil, fmt.Errorf("review: %w", err)
+		}
+
+		switch strings.ToLower(answer) {
+		case "a", "accept", "":
+			accepted = append(accepted, c)
+			i++
+		case "r", "reject":
+			i++
+		case "e", "edit":
+			edited, err := editCard(c)
+			if err != nil {
+				fmt.Printf("%sCouldn't edit the card:%s %v\n", colors.Red, colors.Reset, err)
+				continue
+			}
+			cards[i] = edited
+		case "g", "regenerate":
+			regenerated, err := t.regenerateCard(query, c)
+			if err != nil {
+				fmt.Printf("%sCouldn't regenerate the card:%s %v\n", colors.Red, colors.Reset, err)
+				continue
+			}
+			cards[i] = regenerated
+		case "d", "deck":
+			if err := t.changeDeck(); err != nil {
+				fmt.Printf("%sCouldn't change the deck:%s %v\n", colors.Red, colors.Reset, err)
+			}
+		case "s", "suggestion":
+			if !critiqued || !critique.NeedsRewrite() {
+				fmt.Println("There is no suggestion for this card.")
+				continue
+			}
+			cards = slices.Concat(cards[:i], critique.Rewrites, cards[i+1:])
+			critiques = slices.Concat(critiques[:i], make([]*ai.Critique, len(critique.Rewrites)), critiques[i+1:])
+		case "l", "all":
+			accepted = append(accepted, cards[i:]...)
+			i = len(cards)
+		case "q", "quit":
+			i = len(cards)
+		default:
+			fmt.Printf("Unknown action %q.\n", answer)
+		}
+	}
+
+	slog.Info("cards reviewed", slog.Int("accepted", len(accepted)), slog.Int("generated", len(cards)))
+	return t.deckName, accepted, nil
+}
+
+// refineCards revises the whole card set with the user's feedback, e.g. "merge 2 and 3", until the user
+// has no more feedback. Every revision is shown as a diff against the previous set. It reports whether the
+// set was revised.
+func (t *BasePlugin) refineCards(cards []ai.AnkiCard) ([]ai.AnkiCard, bool, error) {
+	printNumberedCards(cards)
+	revisedAny := false
+	for len(cards) > 0 {
+		feedback, err := readLine("Feedback on the cards, or enter to review them one by one: ")
+		if err != nil {
+			return nil, false, err
+		}
+		if feedback == "" {
+			return cards, revisedAny, nil
+		}
+
+		revised, err := t.reviseCards(cards, feedback)
+		if err != nil {
+			fmt.Printf("%sCouldn't revise the cards:%s %v\n", colors.Red, colors.Reset, err)
+			continue
+		}
+		printCardDiff(ai.DiffCards(cards, revised))
+		cards = revised
+		revisedAny = true
+	}
+	return cards, revisedAny, nil
+}
+
+func (t *BasePlugin) reviseCards(cards []ai.AnkiCard, feedback string) ([]ai.AnkiCard, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), aiTimeout)
+	defer cancel()
+
+	systemPrompt, err := t.renderPrompt(0)
+	if err != nil {
+		return nil, err
+	}
+	revised, err := t.ankiAI.RefineAnkiCards(ctx, t.deckName, cards, feedback, systemPrompt)
+	if err != nil {
+		return nil, err
+	}
+	if len(revised) == 0 {
+		return nil, ErrNoCardGenerated
+	}
+	slog.Info("cards refined", slog.String("feedback", feedback), slog.Int("before", len(cards)), slog.Int("after", len(revised)))
+	return revised, nil
+}
+
+func printNumberedCards(cards []ai.AnkiCard) {
+	fmt.Println()
+	for i, c := range cards {
+		fmt.Printf("%s%d.%s\n%s", colors.Purple, i+1, colors.Reset, colors.BeautifyCard(c))
+	}
+}
+
+// printCardDiff shows what a revision changed, then the full revised set.
+func printCardDiff(changes []ai.CardChange) {
+	fmt.Println()
+	var revised []ai.AnkiCard
+	for _, c := range changes {
+		switch c.Kind {
+		case ai.ChangeAdded:
+			fmt.Printf("%s+ %d. %s%s\n", colors.Green, c.Index+1, c.New.Front, colors.Reset)
+		case ai.ChangeRemoved:
+			fmt.Printf("%s- %s%s\n", colors.Red, c.Old.Front, colors.Reset)
+		case ai.ChangeEdited:
+			fmt.Printf("%s~ %d. %s%s\n", colors.Yellow, c.Index+1, c.New.Front, colors.Reset)
+			if c.Old.Front != c.New.Front {
+				fmt.Printf("    front was: %s\n", c.Old.Front)
+			}
+			if c.Old.Back != c.New.Back {
+				fmt.Printf("    back was:  %s\n", c.Old.Back)
+			}
+		}
+		if c.New != nil {
+			revised = append(revised, *c.New)
+		}
+	}
+	printNumberedCards(revised)
+}
+
+// regenerateCard asks the model for a replacement of a card the user didn't like.
+func (t *BasePlugin) regenerateCard(query string, c ai.AnkiCard) (ai.AnkiCard, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), aiTimeout)
+	defer cancel()
+
+	systemPrompt, err := t.renderPrompt(1)
+	if err != nil {
+		return ai.AnkiCard{}, err
+	}
+	text := fmt.Sprintf("%s\n\nWrite one better card to replace this one:\nFront: %s\nBack: %s", query, c.Front, c.Back)
+	cards, err := t.ankiAI.GenerateAnkiCards(ctx, t.deckName, text, systemPrompt)
+	if err != nil {
+		return ai.AnkiCard{}, err
+	}
+	if len(cards) == 0 {
+		return ai.AnkiCard{}, ErrNoCardGenerated
+	}
+	return cards[0], nil
+}
+
+// changeDeck asks for the deck to store the reviewed cards in.
+func (t *BasePlugin) changeDeck() error {
+	deckName, err := readLine("Deck: ")
+	if err != nil || deckName == "" {
+		return err
+	}
+	_, err = t.useDeck(deckName, "review")
+	return err
+}
+
+// editCard opens the card as json in the user's editor and returns the edited card.
+func editCard(c ai.AnkiCard) (ai.AnkiCard, error) {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return c, err
+	}
+
+	f, err := os.CreateTemp("", "haki-card-*.json")
+	if err != nil {
+		return c, err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return c, err
+	}
+	if err := f.Close(); err != nil {
+		return c, err
+	}
+
+	editor := strings.Fields(editorCommand())
+	cmd := exec.Command(editor[0], append(editor[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return c, fmt.Errorf("run editor: %w", err)
+	}
+
+	data, err = os.ReadFile(f.Name())
+	if err != nil {
+		return c, err
+	}
+	var edited ai.AnkiCard
+	if err := json.Unmarshal(data, &edited); err != nil {
+		return c, fmt.Errorf("decode card: %w", err)
+	}
+	return edited, nil
+}
+
+// editorCommand returns the user's preferred editor.
+func editorCommand() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if e := strings.TrimSpace(os.Getenv(env)); e != "" {
+			return e
+		}
+	}
+	return "vi"
+}
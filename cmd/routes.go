@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/urfave/cli/v2"
+)
+
+var ErrNothingToPrune = errors.New("nothing to prune, pass route ids or --deck")
+
+// routeMemoryThreshold is the similarity a past query needs to reuse its deck without asking the AI.
+const routeMemoryThreshold = 0.6
+
+// RouteEntry is a deck decision haki learned from a previous run.
+type RouteEntry struct {
+	ID      string `json:"id"`
+	Command string `json:"command"`
+	Query   string `json:"query"`
+	Deck    string `json:"deck"`
+	// Suggested is the deck the AI suggested when the user picked a different one.
+	Suggested  string    `json:"suggested,omitempty"`
+	Uses       int       `json:"uses"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// ShortID returns the first block of the route id.
+func (e *RouteEntry) ShortID() string {
+	id, _, _ := strings.Cut(e.ID, "-")
+	return id
+}
+
+// IsCorrection reports whether the user overruled the AI's suggestion.
+func (e *RouteEntry) IsCorrection() bool {
+	return e.Suggested != "" && e.Suggested != e.Deck
+}
+
+// RouteMemory is the on-disk list of learned deck decisions, stored as json in the haki directory.
+// A memory for a command only sees and records that command's decisions.
+type RouteMemory struct {
+	path    string
+	command string
+}
+
+// NewRouteMemory opens the route memory in hakiDir for command. An empty command sees every command's routes.
+func NewRouteMemory(hakiDir, command string) *RouteMemory {
+	return &RouteMemory{path: filepath.Join(hakiDir, "routes.json"), command: command}
+}
+
+// List returns the learned routes, oldest first.
+func (m *RouteMemory) List() ([]*RouteEntry, error) {
+	entries, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(entries, func(e *RouteEntry) bool { return !m.owns(e) }), nil
+}
+
+// Recall returns the route whose query is most similar to query, or nil if none is similar enough.
+func (m *RouteMemory) Recall(query string) (*RouteEntry, error) {
+	entries, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var best *RouteEntry
+	bestScore := routeMemoryThreshold
+	for _, e := range entries {
+		// Later routes win ties, so a correction takes over from the decision it corrects.
+		if score := querySimilarity(query, e.Query); score >= bestScore {
+			best, bestScore = e, score
+		}
+	}
+	return best, nil
+}
+
+// Remember records that query went to deck. suggested is the AI's suggestion when the user overruled it.
+// A query that was seen before has its route updated instead of added again.
+func (m *RouteMemory) Remember(query, deck, suggested string) error {
+	entries, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	key := normalizeQuery(query)
+	idx := slices.IndexFunc(entries, func(e *RouteEntry) bool { return m.owns(e) && normalizeQuery(e.Query) == key })
+	if idx < 0 {
+		entries = append(entries, &RouteEntry{
+			ID:        uuid.NewString(),
+			Command:   m.command,
+			Query:     query,
+			CreatedAt: now,
+		})
+		idx = len(entries) - 1
+	}
+
+	e := entries[idx]
+	if e.Deck != deck {
+		e.Suggested = suggested
+	}
+	e.Deck = deck
+	e.Uses++
+	e.LastUsedAt = now
+	return m.save(entries)
+}
+
+// Prune removes the routes whose id starts with one of ids, and every route to deck if deck isn't empty.
+func (m *RouteMemory) Prune(ids []string, deck string) ([]*RouteEntry, error) {
+	entries, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []*RouteEntry
+	kept := slices.DeleteFunc(entries, func(e *RouteEntry) bool {
+		match := m.owns(e) && ((deck != "" && e.Deck == deck) ||
+			slices.ContainsFunc(ids, func(id string) bool { return id != "" && strings.HasPrefix(e.ID, id) }))
+		if match {
+			pruned = append(pruned, e)
+		}
+		return match
+	})
+	if len(pruned) == 0 {
+		return nil, nil
+	}
+	return pruned, m.save(kept)
+}
+
+func (m *RouteMemory) owns(e *RouteEntry) bool {
+	return m.command == "" || e.Command == m.command
+}
+
+func (m *RouteMemory) load() ([]*RouteEntry, error) {
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []*RouteEntry{}, nil
+		}
+		return nil, fmt.Errorf("read routes: %w", err)
+	}
+
+	var entries []*RouteEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("decode routes: %w", err)
+	}
+	return entries, nil
+}
+
+func (m *RouteMemory) save(entries []*RouteEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode routes: %w", err)
+	}
+	if err := os.WriteFile(m.path, data, 0644); err != nil {
+		return fmt.Errorf("write routes: %w", err)
+	}
+	return nil
+}
+
+// stopWords are too common to say anything about which deck a query belongs in.
+var stopWords = []string{
+	"a", "an", "and", "are", "as", "at", "be", "by", "does", "for", "from", "how", "in", "is", "it",
+	"of", "on", "or", "the", "to", "vs", "what", "when", "why", "with",
+}
+
+// queryKeywords returns the words of the query that aren't stop words.
+func queryKeywords(query string) []string {
+	return slices.DeleteFunc(queryWords(query), func(w string) bool { return slices.Contains(stopWords, w) })
+}
+
+func normalizeQuery(query string) string {
+	return strings.Join(queryWords(query), " ")
+}
+
+// querySimilarity scores two queries between 0 and 1 by averaging the overlap of their keywords,
+// which catches reordered queries, and of their character trigrams, which catches plurals and typos.
+func querySimilarity(a, b string) float64 {
+	ka, kb := queryKeywords(a), queryKeywords(b)
+	if len(ka) == 0 || len(kb) == 0 {
+		return 0
+	}
+	return (jaccard(ka, kb) + jaccard(trigrams(ka), trigrams(kb))) / 2
+}
+
+// trigrams returns the character trigrams of each word, padded so short words still have some.
+func trigrams(words []string) []string {
+	var grams []string
+	for _, w := range words {
+		r := []rune(" " + w + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			grams = append(grams, string(r[i:i+3]))
+		}
+	}
+	return grams
+}
+
+// jaccard returns the size of the intersection of a and b over the size of their union, ignoring repeats.
+func jaccard(a, b []string) float64 {
+	set := make(map[string]int)
+	for _, s := range a {
+		set[s] |= 1
+	}
+	for _, s := range b {
+		set[s] |= 2
+	}
+	both := 0
+	for _, v := range set {
+		if v == 3 {
+			both++
+		}
+	}
+	if len(set) == 0 {
+		return 0
+	}
+	return float64(both) / float64(len(set))
+}
+
+func NewRoutesCommand(hakiDir string) *cli.Command {
+	return &cli.Command{
+		Name:   "routes",
+		Usage:  "List the deck decisions haki has learned from previous runs.",
+		Action: actionRoutes(hakiDir),
+		Subcommands: []*cli.Command{
+			{
+				Name:      "prune",
+				Usage:     "Forget learned deck decisions.",
+				ArgsUsage: "[route...] [--deck <deck>] [--yes]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "deck",
+						Value: "",
+						Usage: "forget every decision for this deck",
+					},
+					newYesFlag(),
+				},
+				Action: actionPruneRoutes(hakiDir),
+			},
+		},
+	}
+}
+
+func actionRoutes(hakiDir string) func(cCtx *cli.Context) error {
+	return func(_ *cli.Context) error {
+		entries, err := NewRouteMemory(hakiDir, "").List()
+		if err != nil {
+			return fmt.Errorf("routes: %w", err)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No routes learned yet.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ROUTE\tCOMMAND\tQUERY\tDECK\tUSES\tLAST USED\tCORRECTED FROM")
+		for _, e := range entries {
+			corrected := ""
+			if e.IsCorrection() {
+				corrected = e.Suggested
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+				e.ShortID(),
+				e.Command,
+				e.Query,
+				e.Deck,
+				e.Uses,
+				e.LastUsedAt.Format(time.DateTime),
+				corrected,
+			)
+		}
+		return w.Flush()
+	}
+}
+
+func actionPruneRoutes(hakiDir string) func(cCtx *cli.Context) error {
+	return func(cCtx *cli.Context) error {
+		if err := runPruneRoutes(hakiDir, cCtx.Args().Slice(), cCtx.String("deck"), cCtx.Bool("yes")); err != nil {
+			slog.Error("run", slog.String("action", "routes prune"), slog.String("error", err.Error()))
+			return err
+		}
+		return nil
+	}
+}
+
+func runPruneRoutes(hakiDir string, ids []string, deck string, skipConfirm bool) error {
+	if len(ids) == 0 && deck == "" {
+		return fmt.Errorf("prune routes: %w", ErrNothingToPrune)
+	}
+	if !skipConfirm {
+		ok, err := confirm("Forget the matching routes?")
+		if err != nil {
+			return fmt.Errorf("prune routes: %w", err)
+		}
+		if !ok {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	pruned, err := NewRouteMemory(hakiDir, "").Prune(ids, deck)
+	if err != nil {
+		return fmt.Errorf("prune routes: %w", err)
+	}
+	for _, e := range pruned {
+		fmt.Printf("Forgot %s: %q -> %s\n", e.ShortID(), e.Query, e.Deck)
+	}
+	fmt.Printf("Forgot %d route(s).\n", len(pruned))
+	return nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrNewDeckDeclined    = errors.New("new deck declined, pick a deck with --deck or pass --allow-new-deck")
+	ErrNewDeckUnconfirmed = errors.New("can't ask before creating a new deck without a terminal, pick a deck with --deck or pass --allow-new-deck")
+	ErrInvalidRoute       = errors.New("invalid route")
+)
+
+// RouteRule sends queries to a deck without asking the AI. A rule matches when its pattern matches the query,
+// or when any of its keywords appears in the query as a whole word. Both are case-insensitive.
+type RouteRule struct {
+	Pattern  string   `json:"pattern,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+	Deck     string   `json:"deck"`
+}
+
+// Match reports whether the rule applies to the query.
+func (r RouteRule) Match(query string) (bool, error) {
+	if r.Deck == "" {
+		return false, fmt.Errorf("%w: rule has no deck", ErrInvalidRoute)
+	}
+	if r.Pattern != "" {
+		re, err := regexp.Compile("(?i)" + r.Pattern)
+		if err != nil {
+			return false, fmt.Errorf("%w (%s): %w", ErrInvalidRoute, r.Deck, err)
+		}
+		if re.MatchString(query) {
+			return true, nil
+		}
+	}
+
+	words := queryWords(query)
+	for _, k := range r.Keywords {
+		if containsPhrase(words, queryWords(k)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// routeDeck returns the deck of the first rule matching the query, or "" if none match.
+func routeDeck(rules []RouteRule, query string) (string, error) {
+	for _, r := range rules {
+		ok, err := r.Match(query)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return r.Deck, nil
+		}
+	}
+	return "", nil
+}
+
+// queryWords splits text into lowercase words.
+func queryWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// containsPhrase reports whether phrase appears as consecutive words in words.
+func containsPhrase(words, phrase []string) bool {
+	if len(phrase) == 0 {
+		return false
+	}
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		match := true
+		for j := range phrase {
+			if words[i+j] != phrase[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
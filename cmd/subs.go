@@ -0,0 +1,377 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/netr/haki/ai"
+	"github.com/netr/haki/anki"
+	"github.com/netr/haki/lib"
+	"github.com/netr/haki/subtitles"
+)
+
+var (
+	ErrSubtitlesRequired = errors.New("subtitle file is required: haki import subs --lang <language> --known <file> <file.srt>")
+	ErrLangRequired      = errors.New("--lang is required")
+	ErrKnownRequired     = errors.New("--known is required")
+	ErrNoMeaning         = errors.New("no meaning generated")
+)
+
+func newSubsImportCommand(apiKey, outputDir string, config func(command string) CommandConfig, profiles []DeckProfile) *cli.Command {
+	return &cli.Command{
+		Name:      "subs",
+		Usage:     "Mine SRT or WebVTT subtitles for sentences with one unknown word and make sentence cards of them.",
+		ArgsUsage: "--lang <es> --known <words.txt> --episode <name> " + generationArgsUsage + " <file.srt>",
+		Flags:     append([]cli.Flag{newLangFlag(), newKnownFlag(), newEpisodeFlag()}, generationFlags()...),
+		Action: actionFn(
+			NewSubsImportAction(
+				apiKey,
+				"subs",
+				outputDir,
+				config("subs"),
+				profiles,
+				append([]string{"lang", "known", "episode"}, generationFlagNames...),
+			)),
+	}
+}
+
+func newLangFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "lang",
+		Value: "",
+		Usage: "language of the subtitles, like es or Spanish",
+	}
+}
+
+func newKnownFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "known",
+		Value: "",
+		Usage: "file of known words, one per line. Sentences with exactly one other word become cards",
+	}
+}
+
+func newEpisodeFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "episode",
+		Value: "",
+		Usage: "episode the cards are tagged with. Defaults to the show and episode in the file name",
+	}
+}
+
+type SubsImportAction struct {
+	Action
+	outputDir string
+	config    CommandConfig
+	profiles  []DeckProfile
+}
+
+func NewSubsImportAction(apiKey, name, outputDir string, cfg CommandConfig, profiles []DeckProfile, flags []string) *SubsImportAction {
+	return &SubsImportAction{
+		Action: Action{
+			flags:  flags,
+			apiKey: apiKey,
+			name:   name,
+		},
+		outputDir: outputDir,
+		config:    cfg.WithDefaults(name),
+		profiles:  profiles,
+	}
+}
+
+// TakesArgs makes the file given after the flags part of Run's arguments.
+func (a SubsImportAction) TakesArgs() bool {
+	return true
+}
+
+func (a SubsImportAction) Run(args ...interface{}) error {
+	if len(args) < 4+len(generationFlagNames) {
+		return fmt.Errorf("action run: %w", ErrSubtitlesRequired)
+	}
+	lang, knownPath, episode := args[0].(string), args[1].(string), args[2].(string)
+	gen, err := parseGenerationArgs(args[3 : 3+len(generationFlagNames)])
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+	path := args[3+len(generationFlagNames)].(string)
+	if lang == "" {
+		return fmt.Errorf("action run: %w", ErrLangRequired)
+	}
+	if knownPath == "" {
+		return fmt.Errorf("action run: %w", ErrKnownRequired)
+	}
+	if episode == "" {
+		episode = subtitles.Episode(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+	cues, err := subtitles.Parse(data)
+	if err != nil {
+		return fmt.Errorf("action run (%s): %w", path, err)
+	}
+	known, err := readKnownWords(knownPath)
+	if err != nil {
+		return fmt.Errorf("action run (%s): %w", knownPath, err)
+	}
+
+	// A word is only mined once, so later episodes skip the words earlier ones made cards for.
+	imports := NewImportLog(a.outputDir)
+	var candidates []subtitles.Candidate
+	for _, c := range subtitles.Mine(subtitles.Sentences(cues), known) {
+		done, err := imports.Imported(a.Name(), wordKey(lang, c.Word))
+		if err != nil {
+			return fmt.Errorf("action run: %w", err)
+		}
+		if !done {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		fmt.Println("No new sentences with a single unknown word.")
+		return nil
+	}
+	slog.Info("sentences mined", slog.String("episode", episode), slog.Int("count", len(candidates)))
+
+	run := NewJournalEntry(a.Name(), path)
+	defer recordRun(a.outputDir, run, gen.Debug)
+
+	if err := a.runSubs(lang, episode, candidates, gen, run, imports); err != nil {
+		return fmt.Errorf("import subs (%s): %w", path, err)
+	}
+	return nil
+}
+
+// readKnownWords reads the known-words list at path.
+func readKnownWords(path string) (subtitles.KnownWords, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return subtitles.ReadKnownWords(f)
+}
+
+// runSubs picks a deck for the episode and makes a sentence card for every candidate, recording each word in
+// the import log once its card is in Anki.
+func (a SubsImportAction) runSubs(lang, episode string, candidates []subtitles.Candidate, gen generationArgs, run *JournalEntry, imports *ImportLog) error {
+	model := a.config.model(gen.Overrides.Model)
+	cardCreator, err := newCardCreatorFunc(a.apiKey)(model)
+	if err != nil {
+		return fmt.Errorf("new openai card creator (%s): %w", model, err)
+	}
+	run.Model = cardCreator.ModelName().String()
+
+	// Every sentence makes exactly one card.
+	gen.Overrides.Count = 1
+	opts := newPluginOptions(a.apiKey, a.outputDir, a.Name(), a.config, a.profiles, gen, run)
+	if tag := anki.NormalizeTag("episode::" + episode); tag != "" {
+		opts.Config.Tags = append(slices.Clone(opts.Config.Tags), tag)
+	}
+	ttsService := ai.NewTTSService(a.apiKey)
+
+	chooser := newSentencePlugin(cardCreator, ttsService, a.outputDir, lang, opts)
+	if !gen.Debug {
+		if err := chooser.Validate(); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), aiTimeout)
+	deckName, err := chooser.ChooseDeck(ctx, subsSummary(lang, episode, candidates))
+	cancel()
+	if err != nil {
+		return err
+	}
+	storedAny := false
+
+	for _, c := range candidates {
+		cardOpts := opts
+		cardOpts.Origin = fmt.Sprintf("%s %s–%s", episode, subtitles.FormatTimestamp(c.Sentence.Start), subtitles.FormatTimestamp(c.Sentence.End))
+		plugin := newSentencePlugin(cardCreator, ttsService, a.outputDir, lang, cardOpts)
+		if err := plugin.runCandidate(deckName, c, gen.Debug); err != nil {
+			return fmt.Errorf("%s: %w", c.Word, err)
+		}
+		// A rejected card leaves the word to be mined again from a later episode.
+		if gen.Debug || !plugin.hasNotes() {
+			continue
+		}
+		storedAny = true
+		if err := imports.Add(a.Name(), wordKey(lang, c.Word)); err != nil {
+			return err
+		}
+	}
+	if storedAny {
+		chooser.rememberDeck(deckName)
+	}
+	return nil
+}
+
+// wordKey identifies a mined word in the import log.
+func wordKey(lang, word string) string {
+	return "word:" + strings.ToLower(lang) + ":" + word
+}
+
+// subsSummary describes an episode by its language and mined words, which is enough to pick a deck for it.
+func subsSummary(lang, episode string, candidates []subtitles.Candidate) string {
+	words := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		words = append(words, c.Word)
+	}
+	return fmt.Sprintf("Vocabulary (%s) from %s: %s", lang, episode, strings.Join(words, ", "))
+}
+
+// SentencePlugin makes a sentence card for a word mined from subtitles: the sentence on the front, read out
+// loud, and the word and its meaning on the back.
+type SentencePlugin struct {
+	*BasePlugin
+	ttsService ai.TTS
+	outputDir  string
+	lang       string
+	candidate  subtitles.Candidate
+	audioPath  string
+}
+
+func newSentencePlugin(cardCreator ai.AnkiController, ttsService ai.TTS, outputDir, lang string, opts PluginOptions) *SentencePlugin {
+	return &SentencePlugin{
+		BasePlugin: NewBasePlugin(cardCreator, opts),
+		ttsService: ttsService,
+		outputDir:  outputDir,
+		lang:       lang,
+	}
+}
+
+func (s *SentencePlugin) ChooseDeck(ctx context.Context, query string) (string, error) {
+	return s.BasePlugin.chooseFilteredDeck(ctx, query)
+}
+
+// runCandidate generates, reviews and stores the card for one mined sentence.
+func (s *SentencePlugin) runCandidate(deckName string, c subtitles.Candidate, skipSave bool) error {
+	if skipSave {
+		if err := s.setDeck(deckName); err != nil {
+			return err
+		}
+	} else if _, err := s.useDeck(deckName, "episode"); err != nil {
+		return err
+	}
+
+	s.candidate = c
+	query := fmt.Sprintf("The word \"%s\" as used in this sentence (%s): \"%s\"\n"+
+		"The front asks for the meaning of the word. The back gives its dictionary form and its meaning in this sentence.",
+		c.Word, s.lang, c.Sentence.Text)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.GenerationTimeout(query))
+	defer cancel()
+	cards, err := s.GenerateAnkiCards(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	if !skipSave {
+		deckName, cards, err = s.ReviewAnkiCards(query, deckName, cards)
+		if err != nil {
+			return err
+		}
+		if err := s.StoreAnkiCards(deckName, cards); err != nil {
+			return err
+		}
+	}
+
+	PrintCards(cards, true)
+	return nil
+}
+
+// GenerateAnkiCards asks the AI for the meaning of the word and puts it on the back of a card with the sentence
+// on the front. The sentence is read out loud when text-to-speech is enabled.
+func (s *SentencePlugin) GenerateAnkiCards(ctx context.Context, query string) ([]ai.AnkiCard, error) {
+	generated, err := s.generateAnkiCards(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("sentence: %w", err)
+	}
+	if len(generated) == 0 {
+		return nil, fmt.Errorf("sentence: %w", ErrNoMeaning)
+	}
+
+	meaning := generated[0]
+	card := ai.AnkiCard{
+		Front:      subtitles.Emphasize(s.candidate.Sentence.Text, s.candidate.Word),
+		Back:       "<b>" + s.candidate.Word + "</b><br>" + meaning.Back,
+		Tags:       meaning.Tags,
+		Extra:      meaning.Extra,
+		Source:     meaning.Source,
+		Confidence: meaning.Confidence,
+	}
+
+	if s.config.ttsEnabled() {
+		if err := s.generateTTS(ctx); err != nil {
+			slog.Error("sentence: create tts", slog.String("error", err.Error()))
+		} else {
+			slog.Info("tts created", slog.String("file_path", s.audioPath))
+		}
+	}
+	return []ai.AnkiCard{card}, nil
+}
+
+func (s *SentencePlugin) generateTTS(ctx context.Context) error {
+	mp3Bytes, err := s.ttsService.GenerateMP3(ctx, s.candidate.Sentence.Text)
+	if err != nil {
+		return fmt.Errorf("generate mp3: %w", err)
+	}
+	path, err := makeTTSFilePath(s.outputDir, s.audioName())
+	if err != nil {
+		return fmt.Errorf("make file path: %w", err)
+	}
+	if err := lib.SaveFile(path, mp3Bytes); err != nil {
+		return fmt.Errorf("save file: %w", err)
+	}
+	s.audioPath = path
+	return nil
+}
+
+// audioName names the sentence's audio, without its extension.
+func (s *SentencePlugin) audioName() string {
+	return "sentence-" + s.candidate.Key()
+}
+
+// StoreAnkiCards stores the cards with the sentence's audio, in the audio field when the note type maps one
+// and after the sentence otherwise.
+func (s *SentencePlugin) StoreAnkiCards(deckName string, cards []ai.AnkiCard) error {
+	noteType, fields := s.noteType()
+	fileName := makeTTSFileName(s.audioName())
+	for _, c := range cards {
+		hasAudio := s.audioPath != "" && !c.IsCloze()
+		if hasAudio && fields.Audio == "" {
+			c.Front += "<br>" + createAudioTag(fileName)
+		}
+		note, err := buildCardNote(deckName, c, noteType, fields)
+		if err != nil {
+			slog.Error("failed building note",
+				slog.String("deck", deckName),
+				slog.String("kind", string(c.Kind)),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+
+		var media []string
+		if hasAudio {
+			if fields.Audio != "" {
+				note.SetField(fields.Audio, createAudioTag(fileName))
+			}
+			note.WithAudio(s.audioPath, fileName)
+			media = append(media, fileName)
+		}
+		if err := s.storeNote(note.Build(), media...); err != nil {
+			return fmt.Errorf("sentence: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/netr/haki/ai"
+	"github.com/netr/haki/document"
+	"github.com/netr/haki/obsidian"
+)
+
+var (
+	ErrVaultRequired = errors.New("vault is required: haki sync obsidian <vault>")
+	ErrNoNotesFound  = errors.New("no notes tagged or foldered for Anki found in the vault")
+)
+
+func NewSyncCommand(apiKey, outputDir string, cfg CommandConfig, profiles []DeckProfile) *cli.Command {
+	return &cli.Command{
+		Name:  "sync",
+		Usage: "Keep Anki in sync with your notes.",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "obsidian",
+				Usage:     "Generate cards for the notes of an Obsidian vault, updating only the sections that changed.",
+				ArgsUsage: "--tag <tag> --folder <folder> " + generationArgsUsage + " <vault>",
+				Flags:     append([]cli.Flag{newVaultTagFlag(), newVaultFolderFlag()}, generationFlags()...),
+				Action: actionFn(
+					NewObsidianSyncAction(
+						apiKey,
+						"obsidian",
+						outputDir,
+						cfg,
+						profiles,
+						append([]string{"tag", "folder"}, generationFlagNames...),
+					)),
+			},
+		},
+	}
+}
+
+func newVaultTagFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "tag",
+		Value: "anki",
+		Usage: "sync notes with this tag or one of its nested tags",
+	}
+}
+
+func newVaultFolderFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "folder",
+		Value: "Anki",
+		Usage: "sync every note in this folder of the vault",
+	}
+}
+
+type ObsidianSyncAction struct {
+	Action
+	outputDir string
+	config    CommandConfig
+	profiles  []DeckProfile
+}
+
+func NewObsidianSyncAction(apiKey, name, outputDir string, cfg CommandConfig, profiles []DeckProfile, flags []string) *ObsidianSyncAction {
+	return &ObsidianSyncAction{
+		Action: Action{
+			flags:  flags,
+			apiKey: apiKey,
+			name:   name,
+		},
+		outputDir: outputDir,
+		config:    cfg.WithDefaults(name),
+		profiles:  profiles,
+	}
+}
+
+// TakesArgs makes the vault given after the flags part of Run's arguments.
+func (a ObsidianSyncAction) TakesArgs() bool {
+	return true
+}
+
+func (a ObsidianSyncAction) Run(args ...interface{}) error {
+	if len(args) < 3+len(generationFlagNames) {
+		return fmt.Errorf("action run: %w", ErrVaultRequired)
+	}
+	tag, folder := args[0].(string), args[1].(string)
+	gen, err := parseGenerationArgs(args[2 : 2+len(generationFlagNames)])
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+	vault := args[2+len(generationFlagNames)].(string)
+	model := a.config.model(gen.Overrides.Model)
+
+	notes, err := obsidian.Find(vault, tag, folder)
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+	if len(notes) == 0 {
+		return fmt.Errorf("action run (%s): %w", vault, ErrNoNotesFound)
+	}
+
+	run := NewJournalEntry(a.Name(), vault)
+	defer recordRun(a.outputDir, run, gen.Debug)
+
+	cardCreator, err := newCardCreatorFunc(a.apiKey)(model)
+	if err != nil {
+		return fmt.Errorf("new openai card creator (%s): %w", model, err)
+	}
+	run.Model = cardCreator.ModelName().String()
+
+	opts := newPluginOptions(a.apiKey, a.outputDir, a.Name(), a.config, a.profiles, gen, run)
+	if !gen.Debug {
+		if err := newTopicPlugin(cardCreator, opts).Validate(); err != nil {
+			return fmt.Errorf("action run: %w", err)
+		}
+	}
+	for _, note := range notes {
+		if err := syncNote(cardCreator, vault, note, gen.Debug, opts); err != nil {
+			return fmt.Errorf("sync (%s): %w", note, err)
+		}
+	}
+	return nil
+}
+
+// syncNote brings the Anki notes made from an Obsidian note up to date. Unchanged sections are skipped, changed
+// sections overwrite the notes they made before and the notes of removed sections are deleted. The state is
+// written back to the note even when a section fails, so the next sync picks up where this one stopped.
+func syncNote(cardCreator ai.AnkiController, vault, note string, skipSave bool, opts PluginOptions) (err error) {
+	path := filepath.Join(vault, note)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	text := string(data)
+	prev, err := obsidian.ReadState(text)
+	if err != nil {
+		return err
+	}
+	sections := document.ParseMarkdown(text)
+	next := obsidian.State{Deck: prev.Deck}
+	changed := false
+
+	defer func() {
+		if err != nil {
+			// Sections that weren't reached keep their notes.
+			next.Sections = append(next.Sections, prev.Sections...)
+		}
+		if skipSave || !changed {
+			return
+		}
+		if werr := writeState(path, next); werr != nil {
+			err = errors.Join(err, werr)
+		}
+	}()
+
+	if next.Deck == "" && len(sections) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), aiTimeout)
+		next.Deck, err = newTopicPlugin(cardCreator, opts).ChooseDeck(ctx, fileSummary(note, sections))
+		cancel()
+		if err != nil {
+			return err
+		}
+		changed = true
+	}
+
+	ankiClient := newAnkiClient()
+	for _, section := range sections {
+		hash := obsidian.Hash(section)
+		old, ok := prev.Take(section.Heading())
+		if ok && old.Hash == hash {
+			next.Sections = append(next.Sections, old)
+			continue
+		}
+
+		sectionOpts := opts
+		sectionOpts.Origin = sectionOrigin(note, section)
+		sectionOpts.Config.Tags = headingTags(opts.Config.Tags, section.Headings)
+		sectionOpts.Replace = old.NoteIDs
+		stored, unused, err := runSection(cardCreator, next.Deck, section, skipSave, sectionOpts)
+		if err != nil {
+			if ok {
+				next.Sections = append(next.Sections, old)
+			}
+			return err
+		}
+		if skipSave {
+			continue
+		}
+		next.Sections = append(next.Sections, obsidian.SectionState{Heading: section.Heading(), Hash: hash, NoteIDs: stored})
+		changed = true
+		if err := ankiClient.Notes().Delete(unused...); err != nil {
+			return err
+		}
+		slog.Info("section synced", slog.String("note", note), slog.String("heading", section.Heading()), slog.Int("notes", len(stored)))
+	}
+
+	if skipSave {
+		return nil
+	}
+	for _, removed := range prev.Sections {
+		if err := ankiClient.Notes().Delete(removed.NoteIDs...); err != nil {
+			return err
+		}
+		changed = true
+		fmt.Printf("Deleted %d note(s) of removed section %q in %s\n", len(removed.NoteIDs), removed.Heading, note)
+	}
+	return nil
+}
+
+// writeState stores the sync state in the frontmatter of the note at path.
+func writeState(path string, state obsidian.State) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	text, err := obsidian.WriteState(string(data), state)
+	if err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(text), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/netr/haki/ai"
+	"github.com/netr/haki/obsidian"
+)
+
+// fakeCardCreator makes one card per request, with the first line of the text as its front.
+type fakeCardCreator struct{}
+
+func (fakeCardCreator) ChooseDeck(context.Context, []string, string) ([]ai.DeckSuggestion, error) {
+	return nil, nil
+}
+
+func (fakeCardCreator) GenerateAnkiCards(_ context.Context, _ string, text string, _ string) ([]ai.AnkiCard, error) {
+	front, _, _ := strings.Cut(text, "\n")
+	return []ai.AnkiCard{{Front: front, Back: "answer"}}, nil
+}
+
+func (fakeCardCreator) RefineAnkiCards(_ context.Context, _ string, cards []ai.AnkiCard, _ string, _ string) ([]ai.AnkiCard, error) {
+	return cards, nil
+}
+
+func (fakeCardCreator) ModelName() ai.ModelNamer {
+	return ai.OpenAIModelName("fake")
+}
+
+// fakeAnki is an AnkiConnect server with one note, existingNoteID, whose front is existingFront.
+type fakeAnki struct {
+	mu      sync.Mutex
+	nextID  float64
+	deleted []float64
+}
+
+const (
+	existingNoteID = 42
+	existingFront  = "Existing question"
+)
+
+func (f *fakeAnki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Action string          `json:"action"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result any
+	switch req.Action {
+	case "deckNames":
+		result = []string{"Haki"}
+	case "modelFieldNames":
+		result = []string{"Front", "Back"}
+	case "findNotes":
+		var params struct{ Query string }
+		_ = json.Unmarshal(req.Params, &params)
+		ids := []float64{}
+		if strings.Contains(params.Query, existingFront) {
+			ids = append(ids, existingNoteID)
+		}
+		result = ids
+	case "notesInfo":
+		var params struct{ Notes []float64 }
+		_ = json.Unmarshal(req.Params, &params)
+		infos := []map[string]any{}
+		for _, id := range params.Notes {
+			if !slices.Contains(f.deleted, id) {
+				infos = append(infos, map[string]any{"noteId": id, "modelName": "Basic", "fields": map[string]any{}})
+			}
+		}
+		result = infos
+	case "addNote":
+		f.nextID++
+		result = 100 + f.nextID
+	case "deleteNotes":
+		var params struct{ Notes []float64 }
+		_ = json.Unmarshal(req.Params, &params)
+		f.deleted = append(f.deleted, params.Notes...)
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"result": result, "error": nil})
+}
+
+func TestSyncNote_KeepsDuplicatesOfExistingNotes(t *testing.T) {
+	anki := &fakeAnki{}
+	server := httptest.NewServer(anki)
+	defer server.Close()
+	t.Setenv("ANKI_CONNECT_URL", server.URL)
+
+	vault := t.TempDir()
+	path := filepath.Join(vault, "note.md")
+	text, err := obsidian.WriteState("# "+existingFront+"\n\nAlready in Anki.\n\n# New question\n\nNot yet.\n", obsidian.State{Deck: "Haki"})
+	if err != nil {
+		t.Fatalf("WriteState: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := PluginOptions{
+		OnDuplicate: DuplicateSkip,
+		Config:      *topicCommandConfig(),
+		Overrides:   Overrides{Review: ReviewNever},
+	}
+	if err := syncNote(fakeCardCreator{}, vault, "note.md", false, opts); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := obsidian.ReadState(string(data))
+	if err != nil {
+		t.Fatalf("ReadState: %v", err)
+	}
+	if len(state.Sections) != 2 {
+		t.Fatalf("Expected 2 synced sections, got %+v", state.Sections)
+	}
+	for _, s := range state.Sections {
+		if slices.Contains(s.NoteIDs, existingNoteID) {
+			t.Errorf("Expected section %q not to own the existing note, got %v", s.Heading, s.NoteIDs)
+		}
+	}
+
+	// Removing the section deletes the notes it created, never the note that was already there.
+	removed := strings.Replace(string(data), "# "+existingFront+"\n\nAlready in Anki.\n\n", "", 1)
+	if err := os.WriteFile(path, []byte(removed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := syncNote(fakeCardCreator{}, vault, "note.md", false, opts); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	if slices.Contains(anki.deleted, existingNoteID) {
+		t.Errorf("Expected the existing note to be kept, deleted %v", anki.deleted)
+	}
+}
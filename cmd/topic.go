@@ -4,81 +4,100 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"time"
 
 	"github.com/urfave/cli/v2"
-
-	"github.com/netr/haki/ai"
 )
 
-func NewTopicCommand(apiKey, outputDir string) *cli.Command {
+func NewTopicCommand(apiKey, outputDir string, cfg CommandConfig, profiles []DeckProfile) *cli.Command {
 	return &cli.Command{
 		Name:      "topic",
 		Usage:     "GenerateAnkiCards a topical Anki card using the specified topic.",
-		ArgsUsage: "--topic <topic> --service <service> --model <model> --debug",
-		Flags: []cli.Flag{
-			newTopicFlag(),
-			newServiceFlag(),
-			newModelFlag(),
-			newDebugFlag(),
-		},
+		ArgsUsage: "--topic <topic> --input <file> " + generationArgsUsage + " [-]",
+		Flags:     append([]cli.Flag{newTopicFlag(), newInputFlag()}, generationFlags()...),
 		Action: actionFn(
 			NewTopicAction(
 				apiKey,
 				"topic",
-				[]string{"topic", "service", "model", "debug"},
+				outputDir,
+				cfg,
+				profiles,
+				append([]string{"topic", "input"}, generationFlagNames...),
 			)),
 	}
 }
 
 func newTopicFlag() *cli.StringFlag {
 	return &cli.StringFlag{
-		Name:     "topic",
-		Aliases:  []string{"t"},
-		Value:    "",
-		Required: true,
-		Usage:    "topic to create a card for",
+		Name:    "topic",
+		Aliases: []string{"t"},
+		Value:   "",
+		Usage:   "topic to create a card for",
 	}
 }
 
 type TopicAction struct {
 	Action
+	outputDir string
+	config    CommandConfig
+	profiles  []DeckProfile
 }
 
-func NewTopicAction(apiKey, name string, flags []string) *TopicAction {
+func NewTopicAction(apiKey, name, outputDir string, cfg CommandConfig, profiles []DeckProfile, flags []string) *TopicAction {
 	return &TopicAction{
-		Action{
+		Action: Action{
 			flags:  flags,
 			apiKey: apiKey,
 			name:   name,
 		},
+		outputDir: outputDir,
+		config:    cfg.WithDefaults(name),
+		profiles:  profiles,
 	}
 }
 
+// TakesArgs makes a "-" given after the flags read topics from stdin.
+func (a TopicAction) TakesArgs() bool {
+	return true
+}
+
 func (a TopicAction) Run(args ...interface{}) error {
-	if len(args) < 1 {
+	if len(args) < 2+len(generationFlagNames) {
 		return fmt.Errorf("action run: %w", ErrQueryRequired)
 	}
-	topic := args[0].(string)
-	service := args[1].(string)
-	model := args[2].(string)
-	debug := args[3].(string)
-
-	skipSave := false
-	if debug == "true" {
-		skipSave = true
+	topics, err := inputItems(args[1].(string), args[2+len(generationFlagNames):])
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+	if topic := args[0].(string); topic != "" {
+		topics = append([]string{topic}, topics...)
 	}
+	if len(topics) == 0 {
+		return ErrTopicRequired
+	}
+	gen, err := parseGenerationArgs(args[2 : 2+len(generationFlagNames)])
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+	model := a.config.model(gen.Overrides.Model)
+	skipSave := gen.Debug
 
 	slog.Info("action",
 		slog.String("action", "topic"),
-		slog.String("topic", topic),
-		slog.String("service", service),
+		slog.Int("topics", len(topics)),
+		slog.String("service", gen.Service),
 		slog.String("model", model),
-		slog.String("debug", debug),
+		slog.Bool("debug", gen.Debug),
+		slog.String("on_duplicate", string(gen.OnDuplicate)),
 	)
 
-	if err := runTopic(a.apiKey, topic, model, skipSave); err != nil {
-		return err
+	run := NewJournalEntry(a.Name(), topics...)
+	defer recordRun(a.outputDir, run, gen.Debug)
+
+	opts := newPluginOptions(a.apiKey, a.outputDir, a.Name(), a.config, a.profiles, gen, run)
+	for _, topic := range topics {
+		if err := runTopic(a.apiKey, topic, model, skipSave, opts); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -86,27 +105,40 @@ func (a TopicAction) Run(args ...interface{}) error {
 // runTopic creates an anki client, card creator and builds the anki card.
 // doesn't need to be part of the action topic struct because the problem terminates after finishing.
 // if we make this a long running program, we should put this in the struct and hold references to the client/creator.
-func runTopic(apiKey, query, model string, skipSave bool) error {
-	cardCreator, err := ai.NewCardCreator(ai.OpenAI, apiKey, ai.OpenAIModelName(model))
+func runTopic(apiKey, query, model string, skipSave bool, opts PluginOptions) error {
+	cardCreator, err := newCardCreatorFunc(apiKey)(model)
 	if err != nil {
 		return fmt.Errorf("new openai card creator (%s): %w", model, err)
 	}
-	plugin := newTopicPlugin(cardCreator)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
+	opts.Run.Model = cardCreator.ModelName().String()
+	plugin := newTopicPlugin(cardCreator, opts)
+	if !skipSave {
+		if err := plugin.Validate(); err != nil {
+			return fmt.Errorf("run topic: %w", err)
+		}
+	}
 
+	// Choosing a deck can wait on the user, so generating cards gets a fresh timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), aiTimeout)
 	deckName, err := plugin.ChooseDeck(ctx, query)
+	cancel()
 	if err != nil {
 		return fmt.Errorf("run topic: %w", err)
 	}
 
+	ctx, cancel = context.WithTimeout(context.Background(), plugin.GenerationTimeout(query))
+	defer cancel()
+
 	cards, err := plugin.GenerateAnkiCards(ctx, query)
 	if err != nil {
 		return fmt.Errorf("run topic: %w", err)
 	}
 
 	if !skipSave {
+		deckName, cards, err = plugin.ReviewAnkiCards(query, deckName, cards)
+		if err != nil {
+			return fmt.Errorf("run topic: %w", err)
+		}
 		if err := plugin.StoreAnkiCards(deckName, cards); err != nil {
 			return fmt.Errorf("run topic: %w", err)
 		}
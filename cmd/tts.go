@@ -17,30 +17,48 @@ func NewTTSCommand(apiKey, hakiDir string) *cli.Command {
 	return &cli.Command{
 		Name:      "tts",
 		Usage:     "GenerateAnkiCards a text-to-speech audio file for the specified word.",
-		ArgsUsage: "--word <word> [--out <output file>]",
-		Flags:     []cli.Flag{newWordsFlag(), newOutFlag()},
+		ArgsUsage: "--words <word,word> --input <file> [--out <output file>] [-]",
+		Flags:     []cli.Flag{newWordsFlag(), newInputFlag(), newOutFlag()},
 		Action:    actionTTS(apiKey, hakiDir),
 	}
 }
 
 func actionTTS(apiKey, hakiDir string) func(cCtx *cli.Context) error {
 	return func(cCtx *cli.Context) error {
-		word := cCtx.String("word")
-		if word == "" {
+		var args []interface{}
+		for _, arg := range cCtx.Args().Slice() {
+			args = append(args, arg)
+		}
+		words, err := inputItems(cCtx.String("input"), args)
+		if err != nil {
+			return err
+		}
+		if w := cCtx.String("words"); w != "" {
+			words = append(splitWords(w), words...)
+		}
+		if len(words) == 0 {
 			return ErrWordFlagRequired
 		}
+
 		output := cCtx.String("out")
 		if output != "" {
+			if len(words) > 1 {
+				return ErrOutWithManyInputs
+			}
 			if err := lib.ValidateOutputPath(output); err != nil {
 				return fmt.Errorf("validate output path: %w", err)
 			}
-		} else {
-			output = fmt.Sprintf("%s/data/%s.mp3", hakiDir, word)
 		}
 
-		if err := runTTS(apiKey, word, output); err != nil {
-			slog.Error("run", slog.String("action", "tts"), slog.String("error", err.Error()))
-			return err
+		for _, word := range words {
+			out := output
+			if out == "" {
+				out = fmt.Sprintf("%s/data/%s.mp3", hakiDir, word)
+			}
+			if err := runTTS(apiKey, word, out); err != nil {
+				slog.Error("run", slog.String("action", "tts"), slog.String("error", err.Error()))
+				return err
+			}
 		}
 		return nil
 	}
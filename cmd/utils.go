@@ -1,9 +1,14 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/netr/haki/ai"
+	"github.com/netr/haki/anki"
+	"github.com/netr/haki/lib"
 )
 
 type AnsiColors struct {
@@ -29,11 +34,79 @@ var colors = AnsiColors{
 }
 
 func (a AnsiColors) BeautifyCard(card ai.AnkiCard) string {
-	return fmt.Sprintf(
-		"%sFront:%s %s%s\n%sBack:%s %s%s\n\n",
-		colors.Blue, colors.Reset,
+	front, back := "Front:", "Back:"
+	if card.IsCloze() {
+		front, back = "Cloze:", "Extra:"
+	} else if card.Kind == ai.CardKindReversed {
+		front = "Front (reversed):"
+	}
+	out := fmt.Sprintf(
+		"%s%s%s %s%s\n%s%s%s %s%s\n",
+		colors.Blue, front, colors.Reset,
 		card.Front, colors.Reset,
-		colors.Green, colors.Reset,
+		colors.Green, back, colors.Reset,
 		card.Back, colors.Reset,
 	)
+	if card.Extra != "" {
+		out += fmt.Sprintf("%sNote:%s %s\n", colors.Cyan, colors.Reset, card.Extra)
+	}
+	if len(card.Tags) > 0 {
+		out += fmt.Sprintf("%sTags:%s %s\n", colors.Yellow, colors.Reset, strings.Join(card.Tags, ", "))
+	}
+	if card.Confidence > 0 && card.Confidence < lowConfidenceThreshold {
+		out += fmt.Sprintf("%sLow confidence:%s %.2f\n", colors.Red, colors.Reset, card.Confidence)
+	}
+	return out + "\n"
+}
+
+// newAnkiClient creates an AnkiConnect client, honoring the ANKI_CONNECT_URL environment variable.
+func newAnkiClient() *anki.Client {
+	return anki.NewClient(lib.GetEnv("ANKI_CONNECT_URL", "http://localhost:8765"))
+}
+
+// stdin is shared by every question, so input buffered for one answer isn't lost to the next.
+var stdin = bufio.NewReader(os.Stdin)
+
+// stdinItems is set once items have been read from stdin, leaving nothing to answer questions with.
+var stdinItems bool
+
+// readLine prints the question and reads the user's answer from stdin, trimmed of surrounding whitespace.
+func readLine(question string) (string, error) {
+	fmt.Print(question)
+	answer, err := stdin.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("reading input: %w", err)
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// confirm asks the user a yes/no question on stdin. Anything other than y/yes is a no.
+func confirm(question string) (bool, error) {
+	answer, err := readLine(question + " [y/N]: ")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// isInteractive reports whether stdin is a terminal not used for items, so the user can answer questions.
+func isInteractive() bool {
+	return !stdinItems && isTerminal(os.Stdin)
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// newCardCreatorFunc returns a CardCreatorFunc for OpenAI models using the given API key.
+func newCardCreatorFunc(apiKey string) CardCreatorFunc {
+	return func(model string) (ai.AnkiController, error) {
+		return ai.NewCardCreator(ai.OpenAI, apiKey, ai.OpenAIModelName(model))
+	}
 }
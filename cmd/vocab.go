@@ -4,30 +4,26 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/urfave/cli/v2"
 
 	"github.com/netr/haki/ai"
 )
 
-func NewVocabCommand(apiKey, outputDir string) *cli.Command {
+func NewVocabCommand(apiKey, outputDir string, cfg CommandConfig, profiles []DeckProfile) *cli.Command {
 	return &cli.Command{
 		Name:      "vocab",
 		Usage:     "GenerateAnkiCards a vocabulary Anki card using the specified word.",
-		ArgsUsage: "--words <word,word> --service <service> --model <model> --debug",
-		Flags: []cli.Flag{
-			newWordsFlag(),
-			newServiceFlag(),
-			newModelFlag(),
-			newDebugFlag(),
-		},
+		ArgsUsage: "--words <word,word> --input <file> " + generationArgsUsage + " [-]",
+		Flags:     append([]cli.Flag{newWordsFlag(), newInputFlag()}, generationFlags()...),
 		Action: actionFn(
 			NewVocabAction(
 				apiKey,
 				"vocab",
 				outputDir,
-				[]string{"words", "service", "model", "debug"},
+				cfg,
+				profiles,
+				append([]string{"words", "input"}, generationFlagNames...),
 			)),
 	}
 }
@@ -35,9 +31,11 @@ func NewVocabCommand(apiKey, outputDir string) *cli.Command {
 type VocabAction struct {
 	Action
 	outputDir string
+	config    CommandConfig
+	profiles  []DeckProfile
 }
 
-func NewVocabAction(apiKey, name, outputDir string, flags []string) *VocabAction {
+func NewVocabAction(apiKey, name, outputDir string, cfg CommandConfig, profiles []DeckProfile, flags []string) *VocabAction {
 	return &VocabAction{
 		Action: Action{
 			flags:  flags,
@@ -45,27 +43,52 @@ func NewVocabAction(apiKey, name, outputDir string, flags []string) *VocabAction
 			name:   name,
 		},
 		outputDir: outputDir,
+		config:    cfg.WithDefaults(name),
+		profiles:  profiles,
 	}
 }
 
+// TakesArgs makes a "-" given after the flags read words from stdin.
+func (a VocabAction) TakesArgs() bool {
+	return true
+}
+
 func (a VocabAction) Run(args ...interface{}) error {
-	if len(args) < 1 {
+	if len(args) < 2+len(generationFlagNames) {
 		return fmt.Errorf("action run: %w", ErrQueryRequired)
 	}
-	words := args[0].(string)
-	if words == "" {
+	items, err := inputItems(args[1].(string), args[2+len(generationFlagNames):])
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+	var words []string
+	if w := args[0].(string); w != "" {
+		words = splitWords(w)
+	}
+	words = append(words, items...)
+	if len(words) == 0 {
 		return ErrWordFlagRequired
 	}
+	gen, err := parseGenerationArgs(args[2 : 2+len(generationFlagNames)])
+	if err != nil {
+		return fmt.Errorf("action run: %w", err)
+	}
+	model := a.config.model(gen.Overrides.Model)
+
+	run := NewJournalEntry(a.Name(), words...)
+	defer recordRun(a.outputDir, run, gen.Debug)
 
-	for _, word := range a.splitWords(words) {
-		if err := runVocab(a.apiKey, word, a.outputDir); err != nil {
+	opts := newPluginOptions(a.apiKey, a.outputDir, a.Name(), a.config, a.profiles, gen, run)
+	for _, word := range words {
+		if err := runVocab(a.apiKey, word, model, a.outputDir, gen.Debug, opts); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (a VocabAction) splitWords(w string) []string {
+// splitWords splits the comma separated words of --words.
+func splitWords(w string) []string {
 	words := []string{w}
 	if strings.Contains(w, ",") {
 		words = strings.Split(w, ",")
@@ -76,30 +99,45 @@ func (a VocabAction) splitWords(w string) []string {
 	return words
 }
 
-func runVocab(apiKey, query, outputDir string) error {
-	cardCreator, err := ai.NewCardCreator(ai.OpenAI, apiKey)
+func runVocab(apiKey, query, model, outputDir string, skipSave bool, opts PluginOptions) error {
+	cardCreator, err := newCardCreatorFunc(apiKey)(model)
 	if err != nil {
-		return fmt.Errorf("new openai api provider: %w", err)
+		return fmt.Errorf("new openai api provider (%s): %w", model, err)
 	}
+	opts.Run.Model = cardCreator.ModelName().String()
 	ttsService := ai.NewTTSService(apiKey)
 	imageGenService := ai.NewImageGenService(apiKey)
-	plugin := newVocabPlugin(cardCreator, ttsService, imageGenService, outputDir)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
+	plugin := newVocabPlugin(cardCreator, ttsService, imageGenService, outputDir, opts)
+	if !skipSave {
+		if err := plugin.Validate(); err != nil {
+			return fmt.Errorf("run vocab: %w", err)
+		}
+	}
 
+	// Choosing a deck can wait on the user, so generating cards gets a fresh timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), aiTimeout)
 	deckName, err := plugin.ChooseDeck(ctx, query)
+	cancel()
 	if err != nil {
 		return fmt.Errorf("run vocab: %w", err)
 	}
 
+	ctx, cancel = context.WithTimeout(context.Background(), plugin.GenerationTimeout(query))
+	defer cancel()
+
 	cards, err := plugin.GenerateAnkiCards(ctx, query)
 	if err != nil {
 		return fmt.Errorf("run vocab: %w", err)
 	}
 
-	if err := plugin.StoreAnkiCards(deckName, cards); err != nil {
-		return fmt.Errorf("run vocab: %w", err)
+	if !skipSave {
+		deckName, cards, err = plugin.ReviewAnkiCards(query, deckName, cards)
+		if err != nil {
+			return fmt.Errorf("run vocab: %w", err)
+		}
+		if err := plugin.StoreAnkiCards(deckName, cards); err != nil {
+			return fmt.Errorf("run vocab: %w", err)
+		}
 	}
 
 	PrintCards(cards, true)
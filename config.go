@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+
+	"github.com/netr/haki/cmd"
 )
 
 var (
@@ -12,16 +14,27 @@ var (
 )
 
 type Config struct {
-	Logger   *ConfigLogger  `json:"logger"`
-	APIKeys  *ConfigApiKeys `json:"api_keys"`
-	fileName string
-	hakiDir  string
+	Logger       *ConfigLogger                 `json:"logger"`
+	APIKeys      *ConfigApiKeys                `json:"api_keys"`
+	Commands     map[string]*cmd.CommandConfig `json:"commands"`
+	DeckProfiles []cmd.DeckProfile             `json:"deck_profiles"`
+	fileName     string
+	hakiDir      string
 }
 
 func (c *Config) Save() error {
 	return saveConfig(c.fileName, c)
 }
 
+// Command returns the config for the named command. Missing settings fall back to the command's defaults.
+func (c *Config) Command(name string) cmd.CommandConfig {
+	var cc cmd.CommandConfig
+	if c.Commands[name] != nil {
+		cc = *c.Commands[name]
+	}
+	return cc.WithDefaults(name)
+}
+
 type ConfigApiKeys struct {
 	OpenAI    string `json:"openai"`
 	Anthropic string `json:"anthropic"`
@@ -66,7 +79,9 @@ func createDefaultConfig(path string) (*Config, error) {
 			OpenAI:    "",
 			Anthropic: "",
 		},
-		fileName: path,
+		Commands:     cmd.DefaultCommandConfigs(),
+		DeckProfiles: []cmd.DeckProfile{},
+		fileName:     path,
 	}
 
 	err := saveConfig(path, cfg)
@@ -0,0 +1,160 @@
+// Package document turns Markdown and HTML files into sections that cards can be generated from.
+//
+// A section is the text under a heading. HTML is converted to Markdown first, so both formats are split the
+// same way, and code blocks are always kept intact.
+package document
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported document format, expected Markdown or HTML")
+
+// Section is the text under a heading.
+type Section struct {
+	// Headings are the headings the section is under, outermost first. Text before the first heading has none.
+	Headings []string
+	// Body is the section's text, without its heading.
+	Body string
+}
+
+// Heading returns the section's heading path, e.g. "TCP > Congestion control".
+func (s Section) Heading() string {
+	return strings.Join(s.Headings, " > ")
+}
+
+// Supported reports whether the file at path is a document Parse understands.
+func Supported(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".html", ".htm":
+		return true
+	default:
+		return false
+	}
+}
+
+// Parse splits a Markdown or HTML document into sections, picking the format by the file extension.
+func Parse(path string, data []byte) ([]Section, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return ParseMarkdown(string(data)), nil
+	case ".html", ".htm":
+		return ParseMarkdown(HTMLToMarkdown(string(data))), nil
+	default:
+		return nil, fmt.Errorf("%s: %w", path, ErrUnsupportedFormat)
+	}
+}
+
+var (
+	headingRegex     = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	frontMatterRegex = regexp.MustCompile(`(?s)\A---\r?\n.*?\r?\n---\r?\n`)
+)
+
+// StripFrontMatter removes a leading YAML front matter block.
+func StripFrontMatter(text string) string {
+	return frontMatterRegex.ReplaceAllString(text, "")
+}
+
+// ParseMarkdown splits Markdown into sections at its headings. Lines in fenced code blocks are never
+// headings, and sections without any text are left out.
+func ParseMarkdown(text string) []Section {
+	var sections []Section
+	var headings []string
+	var body []string
+	fence := ""
+	flush := func() {
+		if b := strings.TrimSpace(strings.Join(body, "\n")); b != "" {
+			sections = append(sections, Section{Headings: headings, Body: b})
+		}
+		body = nil
+	}
+
+	for _, line := range strings.Split(StripFrontMatter(text), "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		default:
+			if m := headingRegex.FindStringSubmatch(trimmed); m != nil {
+				flush()
+				level := min(len(m[1])-1, len(headings))
+				headings = append(headings[:level:level], m[2])
+				continue
+			}
+		}
+		body = append(body, line)
+	}
+	flush()
+	return sections
+}
+
+var (
+	dropRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`(?is)<script\b.*?</script>`),
+		regexp.MustCompile(`(?is)<style\b.*?</style>`),
+		regexp.MustCompile(`(?is)<head\b.*?</head>`),
+		regexp.MustCompile(`(?s)<!--.*?-->`),
+	}
+	preRegex       = regexp.MustCompile(`(?is)<pre\b[^>]*>(.*?)</pre>`)
+	htmlHeadRegex  = regexp.MustCompile(`(?is)<h([1-6])\b[^>]*>(.*?)</h[1-6]>`)
+	listItemRegex  = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	lineBreakRegex = regexp.MustCompile(`(?i)<br\s*/?>`)
+	blockRegex     = regexp.MustCompile(`(?i)</?(p|div|section|article|main|ul|ol|table|tr|blockquote|header|footer)\b[^>]*>`)
+	tagRegex       = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankRegex     = regexp.MustCompile(`\n{3,}`)
+	codeMarker     = regexp.MustCompile("\x00code([0-9]+)\x00")
+)
+
+// HTMLToMarkdown converts the structure of an HTML document to Markdown: headings, paragraphs, list items
+// and code blocks. Other markup is dropped and entities are decoded.
+func HTMLToMarkdown(doc string) string {
+	for _, re := range dropRegexes {
+		doc = re.ReplaceAllString(doc, "")
+	}
+
+	// Code blocks are set aside so the markup they contain as text isn't stripped with the real tags.
+	var code []string
+	doc = preRegex.ReplaceAllStringFunc(doc, func(m string) string {
+		inner := preRegex.FindStringSubmatch(m)[1]
+		code = append(code, "```\n"+strings.Trim(html.UnescapeString(tagRegex.ReplaceAllString(inner, "")), "\n")+"\n```")
+		return "\n\n\x00code" + strconv.Itoa(len(code)-1) + "\x00\n\n"
+	})
+
+	doc = htmlHeadRegex.ReplaceAllStringFunc(doc, func(m string) string {
+		sm := htmlHeadRegex.FindStringSubmatch(m)
+		level, _ := strconv.Atoi(sm[1])
+		return "\n\n" + strings.Repeat("#", level) + " " + inlineText(sm[2]) + "\n\n"
+	})
+	doc = listItemRegex.ReplaceAllString(doc, "\n- ")
+	doc = lineBreakRegex.ReplaceAllString(doc, "\n")
+	doc = blockRegex.ReplaceAllString(doc, "\n\n")
+	doc = html.UnescapeString(tagRegex.ReplaceAllString(doc, ""))
+
+	lines := strings.Split(doc, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	doc = blankRegex.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+
+	doc = codeMarker.ReplaceAllStringFunc(doc, func(m string) string {
+		i, _ := strconv.Atoi(codeMarker.FindStringSubmatch(m)[1])
+		return code[i]
+	})
+	return strings.TrimSpace(doc)
+}
+
+// inlineText returns the text of an HTML fragment on a single line.
+func inlineText(fragment string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(tagRegex.ReplaceAllString(fragment, ""))), " ")
+}
@@ -0,0 +1,70 @@
+package document_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/netr/haki/document"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	text := "---\ntags: [anki]\n---\nIntro.\n\n# TCP\n\nReliable transport.\n\n## Congestion control\n\n```sh\n# not a heading\nss -ti\n```\n\n## Empty\n\n# UDP\n\nUnreliable transport."
+
+	sections := document.ParseMarkdown(text)
+	want := []struct {
+		heading string
+		body    string
+	}{
+		{"", "Intro."},
+		{"TCP", "Reliable transport."},
+		{"TCP > Congestion control", "```sh\n# not a heading\nss -ti\n```"},
+		{"UDP", "Unreliable transport."},
+	}
+	if len(sections) != len(want) {
+		t.Fatalf("Expected %d sections, got %d: %+v", len(want), len(sections), sections)
+	}
+	for i, w := range want {
+		if sections[i].Heading() != w.heading {
+			t.Errorf("Expected section %d heading '%s', got '%s'", i, w.heading, sections[i].Heading())
+		}
+		if sections[i].Body != w.body {
+			t.Errorf("Expected section %d body %q, got %q", i, w.body, sections[i].Body)
+		}
+	}
+}
+
+func TestHTMLToMarkdown(t *testing.T) {
+	doc := `<html><head><title>x</title></head><body>
+<h1>Go <em>maps</em></h1>
+<p>Maps are <b>hash tables</b> &amp; unordered.</p>
+<ul><li>Fast lookups</li><li>No order</li></ul>
+<pre><code>m := map[string]int{}
+if v, ok := m["a"]; ok &amp;&amp; v &lt; 3 {}</code></pre>
+<script>alert(1)</script>
+</body></html>`
+
+	md := document.HTMLToMarkdown(doc)
+	for _, want := range []string{
+		"# Go maps",
+		"Maps are hash tables & unordered.",
+		"- Fast lookups\n- No order",
+		"```\nm := map[string]int{}\nif v, ok := m[\"a\"]; ok && v < 3 {}\n```",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected Markdown to contain %q, got:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "alert") || strings.Contains(md, "<") && !strings.Contains(md, "v < 3") {
+		t.Errorf("Expected scripts and tags to be dropped, got:\n%s", md)
+	}
+}
+
+func TestParse_UnsupportedFormat(t *testing.T) {
+	if _, err := document.Parse("notes.pdf", nil); !errors.Is(err, document.ErrUnsupportedFormat) {
+		t.Errorf("Expected ErrUnsupportedFormat, got %v", err)
+	}
+	if !document.Supported("notes/TCP.MD") {
+		t.Error("Expected .MD files to be supported")
+	}
+}
@@ -0,0 +1,287 @@
+// Package epub reads the chapters of an EPUB book as Markdown.
+//
+// An EPUB is a zip file. META-INF/container.xml points at the OPF package document, whose spine lists the
+// book's XHTML documents in reading order. Chapter titles come from the table of contents, either the EPUB 3
+// navigation document or the EPUB 2 NCX, falling back to the chapter's first heading.
+package epub
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/netr/haki/document"
+)
+
+var (
+	ErrNoPackage        = errors.New("epub has no package document")
+	ErrInvalidSelection = errors.New("invalid chapter selection, expected numbers and ranges like 1,3-5 or all")
+)
+
+// Book is an EPUB book.
+type Book struct {
+	// ID identifies the book: its unique identifier, or a hash of its title and author when it has none.
+	ID       string
+	Title    string
+	Author   string
+	Chapters []Chapter
+}
+
+// Chapter is a document of the book's spine that has text.
+type Chapter struct {
+	// Href is the chapter's path in the EPUB, which identifies it within the book.
+	Href  string
+	Title string
+	// Text is the chapter as Markdown.
+	Text string
+}
+
+// Words returns the number of words in the chapter.
+func (c Chapter) Words() int {
+	return len(strings.Fields(c.Text))
+}
+
+type container struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type opfPackage struct {
+	Titles      []string `xml:"metadata>title"`
+	Creators    []string `xml:"metadata>creator"`
+	Identifiers []string `xml:"metadata>identifier"`
+	Items       []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine struct {
+		Toc      string `xml:"toc,attr"`
+		Itemrefs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type navPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	NavPoints []navPoint `xml:"navPoint"`
+}
+
+type ncx struct {
+	NavPoints []navPoint `xml:"navMap>navPoint"`
+}
+
+// Open reads the book at path.
+func Open(path string) (*Book, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("open epub: %w", err)
+	}
+	defer func() { _ = r.Close() }()
+	return Read(&r.Reader)
+}
+
+// Read reads the book in the zip archive.
+func Read(r *zip.Reader) (*Book, error) {
+	files := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+	read := func(name string) ([]byte, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = rc.Close() }()
+		return io.ReadAll(rc)
+	}
+
+	data, err := read("META-INF/container.xml")
+	if err != nil {
+		return nil, fmt.Errorf("read epub: %w", ErrNoPackage)
+	}
+	var c container
+	if err := xml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("read container: %w", err)
+	}
+	if len(c.Rootfiles) == 0 {
+		return nil, fmt.Errorf("read epub: %w", ErrNoPackage)
+	}
+	opfPath := c.Rootfiles[0].FullPath
+	data, err = read(opfPath)
+	if err != nil {
+		return nil, fmt.Errorf("read epub: %w", ErrNoPackage)
+	}
+	var pkg opfPackage
+	if err := xml.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("read package: %w", err)
+	}
+
+	book := &Book{Title: first(pkg.Titles), Author: first(pkg.Creators), ID: first(pkg.Identifiers)}
+	if book.ID == "" {
+		sum := sha256.Sum256([]byte(book.Title + "\n" + book.Author))
+		book.ID = hex.EncodeToString(sum[:8])
+	}
+
+	base := path.Dir(opfPath)
+	hrefs := map[string]string{}
+	titles := map[string]string{}
+	nav := ""
+	for _, item := range pkg.Items {
+		href := resolve(base, item.Href)
+		hrefs[item.ID] = href
+		switch {
+		case slices.Contains(strings.Fields(item.Properties), "nav"):
+			nav = href
+			if data, err := read(href); err == nil {
+				navTitles(path.Dir(href), string(data), titles)
+			}
+		case item.ID == pkg.Spine.Toc || item.MediaType == "application/x-dtbncx+xml":
+			if data, err := read(href); err == nil {
+				ncxTitles(path.Dir(href), data, titles)
+			}
+		}
+	}
+
+	for _, ref := range pkg.Spine.Itemrefs {
+		href, ok := hrefs[ref.IDRef]
+		if !ok || href == nav {
+			continue
+		}
+		data, err := read(href)
+		if err != nil {
+			continue
+		}
+		text := document.HTMLToMarkdown(string(data))
+		if text == "" {
+			continue
+		}
+
+		title := titles[href]
+		if title == "" {
+			if sections := document.ParseMarkdown(text); len(sections) > 0 && len(sections[0].Headings) > 0 {
+				title = sections[0].Headings[0]
+			}
+		}
+		if title == "" {
+			title = "Chapter " + strconv.Itoa(len(book.Chapters)+1)
+		}
+		book.Chapters = append(book.Chapters, Chapter{Href: href, Title: title, Text: text})
+	}
+	return book, nil
+}
+
+// ncxTitles adds the titles of the NCX table of contents, keyed by document, keeping the first title of each.
+func ncxTitles(dir string, data []byte, titles map[string]string) {
+	var toc ncx
+	if err := xml.Unmarshal(data, &toc); err != nil {
+		return
+	}
+	var walk func([]navPoint)
+	walk = func(points []navPoint) {
+		for _, p := range points {
+			href := resolve(dir, p.Content.Src)
+			if _, ok := titles[href]; !ok && strings.TrimSpace(p.Label) != "" {
+				titles[href] = strings.TrimSpace(p.Label)
+			}
+			walk(p.NavPoints)
+		}
+	}
+	walk(toc.NavPoints)
+}
+
+var (
+	tocNavRegex  = regexp.MustCompile(`(?is)<nav\b[^>]*type="toc"[^>]*>(.*?)</nav>`)
+	navLinkRegex = regexp.MustCompile(`(?is)<a\b[^>]*href="([^"]+)"[^>]*>(.*?)</a>`)
+	tagRegex     = regexp.MustCompile(`<[^>]*>`)
+)
+
+// navTitles adds the titles of an EPUB 3 navigation document, keyed by document, keeping the first title of each.
+func navTitles(dir, nav string, titles map[string]string) {
+	if m := tocNavRegex.FindStringSubmatch(nav); m != nil {
+		nav = m[1]
+	}
+	for _, m := range navLinkRegex.FindAllStringSubmatch(nav, -1) {
+		href := resolve(dir, m[1])
+		title := strings.Join(strings.Fields(tagRegex.ReplaceAllString(m[2], "")), " ")
+		if _, ok := titles[href]; !ok && title != "" {
+			titles[href] = title
+		}
+	}
+}
+
+// resolve returns the path in the archive of an href relative to dir, without its fragment.
+func resolve(dir, href string) string {
+	href, _, _ = strings.Cut(href, "#")
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return path.Join(dir, href)
+}
+
+func first(values []string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// SelectChapters parses a selection of chapters such as "1,3-5" into chapter indexes, in the order given.
+// Chapters are numbered from 1; "all" and an empty selection select every chapter.
+func SelectChapters(selection string, count int) ([]int, error) {
+	selection = strings.TrimSpace(selection)
+	if selection == "" || strings.EqualFold(selection, "all") {
+		indexes := make([]int, count)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes, nil
+	}
+
+	var indexes []int
+	for _, part := range strings.Split(selection, ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		start, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", part, ErrInvalidSelection)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+				return nil, fmt.Errorf("%s: %w", part, ErrInvalidSelection)
+			}
+		}
+		if start < 1 || end > count || start > end {
+			return nil, fmt.Errorf("%s: %w", part, ErrInvalidSelection)
+		}
+		for i := start; i <= end; i++ {
+			if !slices.Contains(indexes, i-1) {
+				indexes = append(indexes, i-1)
+			}
+		}
+	}
+	return indexes, nil
+}
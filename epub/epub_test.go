@@ -0,0 +1,132 @@
+package epub_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/netr/haki/epub"
+)
+
+func newEPUB(t *testing.T, files map[string]string) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+const opf = `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>Networking Basics</dc:title>
+    <dc:creator>Ada Lovelace</dc:creator>
+    <dc:identifier>urn:isbn:123</dc:identifier>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="cover" href="text/cover.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c1" href="text/chapter%201.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c2" href="text/c2.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c3" href="text/c3.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine toc="ncx">
+    <itemref idref="nav"/>
+    <itemref idref="cover"/>
+    <itemref idref="c2"/>
+    <itemref idref="c1"/>
+    <itemref idref="c3"/>
+  </spine>
+</package>`
+
+func TestRead(t *testing.T) {
+	r := newEPUB(t, map[string]string{
+		"mimetype":               "application/epub+zip",
+		"META-INF/container.xml": `<container><rootfiles><rootfile full-path="OEBPS/content.opf"/></rootfiles></container>`,
+		"OEBPS/content.opf":      opf,
+		"OEBPS/nav.xhtml":        `<html><body><nav epub:type="toc"><ol><li><a href="text/chapter%201.xhtml">1. <b>Packets</b></a></li></ol></nav></body></html>`,
+		"OEBPS/toc.ncx": `<ncx><navMap>
+			<navPoint><navLabel><text>Ignored, nav wins</text></navLabel><content src="text/chapter%201.xhtml#start"/></navPoint>
+			<navPoint><navLabel><text>Routing</text></navLabel><content src="text/c2.xhtml"/></navPoint>
+		</navMap></ncx>`,
+		"OEBPS/text/cover.xhtml":     `<html><body><img src="cover.jpg"/></body></html>`,
+		"OEBPS/text/chapter 1.xhtml": `<html><body><h1>Packets</h1><p>Data is sent in packets.</p></body></html>`,
+		"OEBPS/text/c2.xhtml":        `<html><body><p>Routers forward packets.</p></body></html>`,
+		"OEBPS/text/c3.xhtml":        `<html><body><h2>Appendix &amp; Notes</h2><p>More.</p></body></html>`,
+	})
+
+	book, err := epub.Read(r)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if book.Title != "Networking Basics" || book.Author != "Ada Lovelace" || book.ID != "urn:isbn:123" {
+		t.Errorf("Unexpected metadata: %+v", book)
+	}
+
+	var titles []string
+	for _, c := range book.Chapters {
+		titles = append(titles, c.Title)
+	}
+	// The navigation document and the cover have no text of their own, and chapters follow the spine.
+	expected := []string{"Routing", "1. Packets", "Appendix & Notes"}
+	if !slices.Equal(titles, expected) {
+		t.Fatalf("Expected chapters %v, got %v", expected, titles)
+	}
+	if book.Chapters[1].Href != "OEBPS/text/chapter 1.xhtml" {
+		t.Errorf("Expected hrefs to be resolved, got '%s'", book.Chapters[1].Href)
+	}
+	if book.Chapters[1].Text != "# Packets\n\nData is sent in packets." || book.Chapters[1].Words() != 7 {
+		t.Errorf("Unexpected chapter text: %q", book.Chapters[1].Text)
+	}
+}
+
+func TestRead_NoPackage(t *testing.T) {
+	if _, err := epub.Read(newEPUB(t, map[string]string{"mimetype": "application/epub+zip"})); !errors.Is(err, epub.ErrNoPackage) {
+		t.Errorf("Expected ErrNoPackage, got %v", err)
+	}
+}
+
+func TestSelectChapters(t *testing.T) {
+	tests := map[string][]int{
+		"":          {0, 1, 2, 3, 4},
+		"all":       {0, 1, 2, 3, 4},
+		"2":         {1},
+		"1,3-5":     {0, 2, 3, 4},
+		" 4 , 2-3 ": {3, 1, 2},
+		"2,2":       {1},
+	}
+	for selection, expected := range tests {
+		actual, err := epub.SelectChapters(selection, 5)
+		if err != nil {
+			t.Errorf("SelectChapters(%q): %v", selection, err)
+			continue
+		}
+		if !slices.Equal(actual, expected) {
+			t.Errorf("SelectChapters(%q) = %v, want %v", selection, actual, expected)
+		}
+	}
+
+	for _, selection := range []string{"0", "6", "3-1", "a", "1-"} {
+		if _, err := epub.SelectChapters(selection, 5); !errors.Is(err, epub.ErrInvalidSelection) {
+			t.Errorf("SelectChapters(%q): expected ErrInvalidSelection, got %v", selection, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Package grounding checks that quotes cited by generated cards actually appear in the text they came from.
+//
+// Models tend to tidy up the quotes they cite, so matching is done on words rather than characters:
+// case, punctuation and whitespace are ignored, and a few words may differ.
+package grounding
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultThreshold is the similarity a quote needs to count as found in the text.
+const DefaultThreshold = 0.85
+
+// anchorWords is how many of the quote's first words are tried as the start of a match.
+const anchorWords = 3
+
+var wordRegex = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// Match is the span of the text that best matches a quote.
+type Match struct {
+	// Text is the span, verbatim from the text.
+	Text string
+	// Score is how similar the span is to the quote, from 0 to 1.
+	Score float64
+}
+
+type word struct {
+	norm       string
+	start, end int
+}
+
+func words(text string) []word {
+	var ws []word
+	for _, loc := range wordRegex.FindAllStringIndex(text, -1) {
+		ws = append(ws, word{norm: strings.ToLower(text[loc[0]:loc[1]]), start: loc[0], end: loc[1]})
+	}
+	return ws
+}
+
+// Find returns the span of text most similar to quote. The score is zero when no span shares a word with the quote.
+func Find(text, quote string) Match {
+	tw, qw := words(text), words(quote)
+	if len(tw) == 0 || len(qw) == 0 {
+		return Match{}
+	}
+	quoteNorm := make([]string, len(qw))
+	for i, w := range qw {
+		quoteNorm[i] = w.norm
+	}
+
+	var best Match
+	for start := range tw {
+		// Only spans starting on one of the quote's first words can match, which keeps long texts fast.
+		if !slices.Contains(quoteNorm[:min(anchorWords, len(quoteNorm))], tw[start].norm) {
+			continue
+		}
+		for n := max(1, len(qw)-2); n <= len(qw)+2 && start+n <= len(tw); n++ {
+			span := make([]string, n)
+			for i := range span {
+				span[i] = tw[start+i].norm
+			}
+			score := similarity(quoteNorm, span)
+			if score > best.Score {
+				best = Match{Text: text[tw[start].start:tw[start+n-1].end], Score: score}
+			}
+		}
+		if best.Score == 1 {
+			break
+		}
+	}
+	return best
+}
+
+// Verify reports whether quote appears in text, allowing for small differences.
+func Verify(text, quote string, threshold float64) (Match, bool) {
+	m := Find(text, quote)
+	return m, m.Score > 0 && m.Score >= threshold
+}
+
+// similarity is one minus the word edit distance between a and b, relative to the longer of the two.
+func similarity(a, b []string) float64 {
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(editDistance(a, b))/float64(longest)
+}
+
+func editDistance(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,49 @@
+package grounding_test
+
+import (
+	"testing"
+
+	"github.com/netr/haki/grounding"
+)
+
+const text = `TCP uses a congestion window to limit how much data is in flight.
+During slow start, the congestion window doubles every round trip until it reaches the slow start threshold.`
+
+func TestFind_Exact(t *testing.T) {
+	m := grounding.Find(text, "the congestion window doubles every round trip")
+	if m.Score != 1 {
+		t.Errorf("Expected a score of 1, got %v", m.Score)
+	}
+	if m.Text != "the congestion window doubles every round trip" {
+		t.Errorf("Expected the span to be verbatim, got '%s'", m.Text)
+	}
+}
+
+func TestFind_IgnoresCaseAndPunctuation(t *testing.T) {
+	m := grounding.Find(text, "During slow-start the Congestion Window doubles every round trip...")
+	if m.Score != 1 {
+		t.Errorf("Expected a score of 1, got %v", m.Score)
+	}
+	if m.Text != "During slow start, the congestion window doubles every round trip" {
+		t.Errorf("Expected the span to be taken from the text, got '%s'", m.Text)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name  string
+		quote string
+		want  bool
+	}{
+		{name: "one word changed", quote: "TCP uses a congestion window to limit how much data is in transit", want: true},
+		{name: "not in the text", quote: "TCP Reno halves the window after three duplicate acknowledgements", want: false},
+		{name: "empty quote", quote: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := grounding.Verify(text, tt.quote, grounding.DefaultThreshold); ok != tt.want {
+				t.Errorf("Verify() = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
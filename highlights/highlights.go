@@ -0,0 +1,169 @@
+// Package highlights reads highlights exported as CSV, by Readwise or by any tool whose columns are mapped.
+package highlights
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidColumns    = errors.New("invalid column mapping, expected attribute=column pairs like highlight=Quote,title=Book")
+	ErrNoHighlightColumn = errors.New("csv has no highlight column")
+)
+
+// Highlight is a passage highlighted in a book or article, with the reader's note on it.
+type Highlight struct {
+	Text     string
+	Note     string
+	Title    string
+	Author   string
+	Location string
+}
+
+// Key identifies a highlight by its text and where it is from, so it is only imported once.
+func (h Highlight) Key() string {
+	sum := sha256.Sum256([]byte(h.Title + "\n" + h.Text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Columns are the names of the CSV columns each attribute of a highlight is read from. Only Highlight is
+// required. Column names are matched ignoring case.
+type Columns struct {
+	Highlight string
+	Note      string
+	Title     string
+	Author    string
+	Location  string
+}
+
+// ReadwiseColumns are the columns of Readwise's CSV export.
+var ReadwiseColumns = Columns{
+	Highlight: "Highlight",
+	Note:      "Note",
+	Title:     "Book Title",
+	Author:    "Book Author",
+	Location:  "Location",
+}
+
+// DefaultColumns are used for the attributes a generic CSV's mapping leaves out.
+var DefaultColumns = Columns{
+	Highlight: "highlight",
+	Note:      "note",
+	Title:     "title",
+	Author:    "author",
+	Location:  "location",
+}
+
+// ParseColumns parses a column mapping such as "highlight=Quote,title=Book". Attributes it leaves out keep
+// their column in def.
+func ParseColumns(mapping string, def Columns) (Columns, error) {
+	cols := def
+	for _, pair := range strings.Split(mapping, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		attr, column, ok := strings.Cut(pair, "=")
+		column = strings.TrimSpace(column)
+		if !ok || column == "" {
+			return Columns{}, fmt.Errorf("%s: %w", pair, ErrInvalidColumns)
+		}
+		switch strings.ToLower(strings.TrimSpace(attr)) {
+		case "highlight", "text":
+			cols.Highlight = column
+		case "note":
+			cols.Note = column
+		case "title":
+			cols.Title = column
+		case "author":
+			cols.Author = column
+		case "location":
+			cols.Location = column
+		default:
+			return Columns{}, fmt.Errorf("%s: %w", pair, ErrInvalidColumns)
+		}
+	}
+	return cols, nil
+}
+
+// ReadCSV reads the highlights of a CSV file with a header row. Rows without highlight text are skipped.
+// Highlights from the same title are kept together, in the order of their location when it is a number.
+func ReadCSV(r io.Reader, cols Columns) ([]Highlight, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+	index := func(name string) int {
+		for i, h := range header {
+			if name != "" && strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")), name) {
+				return i
+			}
+		}
+		return -1
+	}
+	text, note, title, author, location := index(cols.Highlight), index(cols.Note), index(cols.Title), index(cols.Author), index(cols.Location)
+	if text < 0 {
+		return nil, fmt.Errorf("%s: %w", cols.Highlight, ErrNoHighlightColumn)
+	}
+
+	var highlights []Highlight
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		h := Highlight{
+			Text:     field(text),
+			Note:     field(note),
+			Title:    field(title),
+			Author:   field(author),
+			Location: field(location),
+		}
+		if h.Text != "" {
+			highlights = append(highlights, h)
+		}
+	}
+
+	sortByTitle(highlights)
+	return highlights, nil
+}
+
+// sortByTitle groups highlights by title, in the order titles first appear, and orders each title's
+// highlights by location.
+func sortByTitle(highlights []Highlight) {
+	order := map[string]int{}
+	for _, h := range highlights {
+		if _, ok := order[h.Title]; !ok {
+			order[h.Title] = len(order)
+		}
+	}
+	sort.SliceStable(highlights, func(i, j int) bool {
+		a, b := highlights[i], highlights[j]
+		if a.Title != b.Title {
+			return order[a.Title] < order[b.Title]
+		}
+		la, errA := strconv.Atoi(a.Location)
+		lb, errB := strconv.Atoi(b.Location)
+		return errA == nil && errB == nil && la < lb
+	})
+}
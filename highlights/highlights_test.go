@@ -0,0 +1,68 @@
+package highlights_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/netr/haki/highlights"
+)
+
+func TestReadCSV_Readwise(t *testing.T) {
+	data := "\ufeffHighlight,Book Title,Book Author,Amazon Book ID,Note,Color,Tags,Location Type,Location,Highlighted at,Document tags\n" +
+		"\"Later, in the book.\",Dune,Frank Herbert,B1,,yellow,,location,900,2023-01-02,\n" +
+		"\"Fear is the mind-killer.\",Dune,Frank Herbert,B1,\"Litany, again\",yellow,,location,120,2023-01-01,\n" +
+		"Words are pale shadows.,The Name of the Wind,Patrick Rothfuss,B2,,,,location,42,2023-01-03,\n" +
+		",Dune,Frank Herbert,B1,empty,,,location,5,,\n" +
+		"Earlier.,Dune,Frank Herbert,B1,,,,location,7,,\n"
+
+	hs, err := highlights.ReadCSV(strings.NewReader(data), highlights.ReadwiseColumns)
+	if err != nil {
+		t.Fatalf("ReadCSV: %v", err)
+	}
+	var texts []string
+	for _, h := range hs {
+		texts = append(texts, h.Text)
+	}
+	expected := "Earlier.|Fear is the mind-killer.|Later, in the book.|Words are pale shadows."
+	if strings.Join(texts, "|") != expected {
+		t.Fatalf("Expected highlights grouped by title in location order, got %v", texts)
+	}
+
+	h := hs[1]
+	if h.Note != "Litany, again" || h.Title != "Dune" || h.Author != "Frank Herbert" || h.Location != "120" {
+		t.Errorf("Unexpected highlight: %+v", h)
+	}
+	if hs[0].Key() == hs[1].Key() {
+		t.Error("Expected different highlights to have different keys")
+	}
+}
+
+func TestReadCSV_MappedColumns(t *testing.T) {
+	cols, err := highlights.ParseColumns("highlight=Quote, title=Source", highlights.DefaultColumns)
+	if err != nil {
+		t.Fatalf("ParseColumns: %v", err)
+	}
+	data := "quote,source,AUTHOR,note\nTCP is reliable.,Networking,Ada,check this\n"
+
+	hs, err := highlights.ReadCSV(strings.NewReader(data), cols)
+	if err != nil {
+		t.Fatalf("ReadCSV: %v", err)
+	}
+	expected := highlights.Highlight{Text: "TCP is reliable.", Note: "check this", Title: "Networking", Author: "Ada"}
+	if len(hs) != 1 || hs[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, hs)
+	}
+
+	if _, err := highlights.ReadCSV(strings.NewReader("text,title\na,b\n"), cols); !errors.Is(err, highlights.ErrNoHighlightColumn) {
+		t.Errorf("Expected ErrNoHighlightColumn, got %v", err)
+	}
+}
+
+func TestParseColumns_Invalid(t *testing.T) {
+	for _, mapping := range []string{"highlight", "page=Page", "title="} {
+		if _, err := highlights.ParseColumns(mapping, highlights.DefaultColumns); !errors.Is(err, highlights.ErrInvalidColumns) {
+			t.Errorf("ParseColumns(%q): expected ErrInvalidColumns, got %v", mapping, err)
+		}
+	}
+}
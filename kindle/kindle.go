@@ -0,0 +1,216 @@
+// Package kindle reads what a Kindle collects while reading: highlights and notes from "My Clippings.txt", and
+// the words looked up in the Vocabulary Builder database, vocab.db.
+package kindle
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	// The Vocabulary Builder database is SQLite.
+	_ "modernc.org/sqlite"
+)
+
+// Paths of the clippings file and the vocabulary database on a mounted Kindle.
+var (
+	ClippingsPath = filepath.Join("documents", "My Clippings.txt")
+	VocabPath     = filepath.Join("system", "vocabulary", "vocab.db")
+)
+
+// ClippingKind is the kind of a clipping.
+type ClippingKind string
+
+const (
+	KindHighlight ClippingKind = "highlight"
+	KindNote      ClippingKind = "note"
+	KindBookmark  ClippingKind = "bookmark"
+)
+
+// Clipping is an entry of "My Clippings.txt".
+type Clipping struct {
+	Title    string
+	Author   string
+	Kind     ClippingKind
+	Location string
+	Added    string
+	Text     string
+}
+
+// Key identifies a clipping, so it is only imported once.
+func (c Clipping) Key() string {
+	sum := sha256.Sum256([]byte(c.Title + "\n" + c.Location + "\n" + c.Text))
+	return hex.EncodeToString(sum[:8])
+}
+
+const clippingSeparator = "=========="
+
+var (
+	authorRegex   = regexp.MustCompile(`^(.*?)\s*\(([^()]*)\)\s*$`)
+	locationRegex = regexp.MustCompile(`(?i)\b(?:location|loc\.)\s+([0-9]+(?:-[0-9]+)?)`)
+	pageRegex     = regexp.MustCompile(`(?i)\bpage\s+([0-9ivxlc]+(?:-[0-9ivxlc]+)?)`)
+)
+
+// ParseClippings parses the contents of "My Clippings.txt". Bookmarks and empty clippings are left out.
+func ParseClippings(text string) []Clipping {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var clippings []Clipping
+	for _, entry := range strings.Split(text, clippingSeparator) {
+		lines := strings.Split(strings.Trim(entry, "\n\ufeff "), "\n")
+		if len(lines) < 3 {
+			continue
+		}
+		c := Clipping{Text: strings.TrimSpace(strings.Join(lines[2:], "\n"))}
+		c.Title = strings.TrimSpace(strings.TrimPrefix(lines[0], "\ufeff"))
+		if m := authorRegex.FindStringSubmatch(c.Title); m != nil {
+			c.Title, c.Author = m[1], m[2]
+		}
+
+		meta := strings.TrimSpace(strings.TrimPrefix(lines[1], "- "))
+		lower := strings.ToLower(meta)
+		switch {
+		case strings.Contains(lower, "bookmark"):
+			c.Kind = KindBookmark
+		case strings.Contains(lower, "note"):
+			c.Kind = KindNote
+		default:
+			c.Kind = KindHighlight
+		}
+		if m := locationRegex.FindStringSubmatch(meta); m != nil {
+			c.Location = m[1]
+		} else if m := pageRegex.FindStringSubmatch(meta); m != nil {
+			c.Location = "page " + m[1]
+		}
+		if i := strings.LastIndex(meta, "|"); i >= 0 {
+			c.Added = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(meta[i+1:]), "Added on"))
+		}
+
+		if c.Kind == KindBookmark || c.Text == "" {
+			continue
+		}
+		clippings = append(clippings, c)
+	}
+	return clippings
+}
+
+// ReadClippings reads and parses a clippings file.
+func ReadClippings(path string) ([]Clipping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read clippings: %w", err)
+	}
+	return ParseClippings(string(data)), nil
+}
+
+// Lookup is a word looked up while reading, with the sentence it was used in.
+type Lookup struct {
+	// ID identifies the word, so it is only imported once however often it was looked up.
+	ID       string
+	Word     string
+	Stem     string
+	Language string
+	Usage    string
+	Book     string
+	Author   string
+}
+
+// ErrNotSQLite is returned for a vocabulary database that isn't a SQLite database.
+var ErrNotSQLite = errors.New("not a SQLite database")
+
+// sqliteMagic is the header every SQLite database starts with.
+const sqliteMagic = "SQLite format 3\x00"
+
+// vocabQuery returns every lookup with its word and book, in the order they were made.
+const vocabQuery = `SELECT l.word_key, w.word, w.stem, w.lang, l.usage, b.title, b.authors
+FROM LOOKUPS l
+JOIN WORDS w ON w.id = l.word_key
+LEFT JOIN BOOK_INFO b ON b.id = l.book_key
+ORDER BY l.timestamp, l.rowid`
+
+// ReadVocab reads the lookups of a Vocabulary Builder database. Each word is returned once, with the usage of
+// its first lookup, in the order the words were looked up. The database is opened read-only, and lookups the
+// Kindle hasn't checkpointed from its write-ahead log yet are read too.
+func ReadVocab(path string) ([]Lookup, error) {
+	if err := checkSQLite(path); err != nil {
+		return nil, fmt.Errorf("read vocab: %w", err)
+	}
+	uri, err := readOnlyURI(path)
+	if err != nil {
+		return nil, fmt.Errorf("read vocab: %w", err)
+	}
+	db, err := sql.Open("sqlite", uri)
+	if err != nil {
+		return nil, fmt.Errorf("read vocab: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	rows, err := db.Query(vocabQuery)
+	if err != nil {
+		return nil, fmt.Errorf("read vocab: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	seen := make(map[string]bool)
+	var result []Lookup
+	for rows.Next() {
+		var id string
+		var word, stem, lang, usage, title, authors sql.NullString
+		if err := rows.Scan(&id, &word, &stem, &lang, &usage, &title, &authors); err != nil {
+			return nil, fmt.Errorf("read vocab: %w", err)
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, Lookup{
+			ID:       id,
+			Word:     word.String,
+			Stem:     stem.String,
+			Language: lang.String,
+			Usage:    strings.TrimSpace(usage.String),
+			Book:     title.String,
+			Author:   authors.String,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read vocab: %w", err)
+	}
+	return result, nil
+}
+
+// checkSQLite makes sure the file at path is a SQLite database, which the driver only finds out on the first
+// query, with a less helpful error.
+func checkSQLite(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	header := make([]byte, len(sqliteMagic))
+	if _, err := io.ReadFull(f, header); err != nil || string(header) != sqliteMagic {
+		return fmt.Errorf("%s: %w", path, ErrNotSQLite)
+	}
+	return nil
+}
+
+// readOnlyURI returns the SQLite URI that opens the database at path read-only.
+func readOnlyURI(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	// Windows paths like C:/vocab.db need a leading slash to be a URI path.
+	slashed := filepath.ToSlash(abs)
+	if !strings.HasPrefix(slashed, "/") {
+		slashed = "/" + slashed
+	}
+	return (&url.URL{Scheme: "file", Path: slashed, RawQuery: "mode=ro"}).String(), nil
+}
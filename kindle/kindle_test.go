@@ -0,0 +1,138 @@
+package kindle_test
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/netr/haki/kindle"
+)
+
+func TestParseClippings(t *testing.T) {
+	text := "\ufeffThe Name of the Wind (Patrick Rothfuss)\r\n" +
+		"- Your Highlight on page 12 | Location 170-172 | Added on Sunday, 1 January 2023 10:00:00\r\n\r\n" +
+		"Words are pale shadows of forgotten names.\r\n==========\r\n" +
+		"Dune (Frank Herbert)\r\n" +
+		"- Your Bookmark on Location 300 | Added on Monday, 2 January 2023 09:00:00\r\n\r\n\r\n==========\r\n" +
+		"Dune (Frank Herbert)\r\n" +
+		"- Your Note on Location 310 | Added on Monday, 2 January 2023 09:05:00\r\n\r\n" +
+		"Fear is the mind-killer.\r\n==========\r\n"
+
+	clippings := kindle.ParseClippings(text)
+	if len(clippings) != 2 {
+		t.Fatalf("Expected 2 clippings, got %d: %+v", len(clippings), clippings)
+	}
+
+	c := clippings[0]
+	if c.Title != "The Name of the Wind" || c.Author != "Patrick Rothfuss" {
+		t.Errorf("Expected title and author to be split, got '%s' by '%s'", c.Title, c.Author)
+	}
+	if c.Kind != kindle.KindHighlight || c.Location != "170-172" || c.Added != "Sunday, 1 January 2023 10:00:00" {
+		t.Errorf("Unexpected metadata: %+v", c)
+	}
+	if c.Text != "Words are pale shadows of forgotten names." {
+		t.Errorf("Unexpected text: %q", c.Text)
+	}
+	if clippings[1].Kind != kindle.KindNote || clippings[1].Location != "310" {
+		t.Errorf("Expected a note at location 310, got %+v", clippings[1])
+	}
+	if clippings[0].Key() == clippings[1].Key() {
+		t.Error("Expected different clippings to have different keys")
+	}
+}
+
+func TestReadVocab(t *testing.T) {
+	lookups, err := kindle.ReadVocab("testdata/vocab.db")
+	if err != nil {
+		t.Fatalf("ReadVocab: %v", err)
+	}
+	// Two words, 300 fillers spread over several pages, and one usage long enough to overflow its page.
+	if len(lookups) != 303 {
+		t.Fatalf("Expected 303 words, got %d", len(lookups))
+	}
+
+	first := lookups[0]
+	expected := kindle.Lookup{
+		ID:       "en:cacophony",
+		Word:     "cacophonies",
+		Stem:     "cacophony",
+		Language: "en",
+		Usage:    "The cacophonies of the market faded as I walked.",
+		Book:     "The Name of the Wind",
+		Author:   "Patrick Rothfuss",
+	}
+	if first != expected {
+		t.Errorf("Expected %+v, got %+v", expected, first)
+	}
+	if lookups[1].Word != "sietch" || lookups[1].Book != "Dune" {
+		t.Errorf("Expected lookups in the order they were made, got %+v", lookups[1])
+	}
+
+	long := lookups[len(lookups)-1]
+	if !strings.HasPrefix(long.Usage, "A long sentence xxx") || !strings.HasSuffix(long.Usage, "x end.") || len(long.Usage) != 3021 {
+		t.Errorf("Expected the overflowing usage to be read in full, got %d bytes", len(long.Usage))
+	}
+}
+
+func TestReadVocab_WriteAheadLog(t *testing.T) {
+	data, err := os.ReadFile("testdata/vocab.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vocab.db")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A Kindle that is still writing keeps its latest lookups in vocab.db-wal.
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		"PRAGMA journal_mode=WAL",
+		"PRAGMA wal_autocheckpoint=0",
+		"INSERT INTO WORDS (id, word, stem, lang) VALUES ('en:ephemeral', 'ephemeral', 'ephemeral', 'en')",
+		"INSERT INTO LOOKUPS (id, word_key, usage, timestamp) VALUES ('wal', 'en:ephemeral', 'An ephemeral lookup.', 9999999999999)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	// Copied before the writer closes, so nothing is checkpointed into the copy.
+	copied := filepath.Join(t.TempDir(), "vocab.db")
+	for _, suffix := range []string{"", "-wal"} {
+		data, err := os.ReadFile(path + suffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(copied+suffix, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lookups, err := kindle.ReadVocab(copied)
+	if err != nil {
+		t.Fatalf("ReadVocab: %v", err)
+	}
+	if last := lookups[len(lookups)-1]; last.Word != "ephemeral" {
+		t.Errorf("Expected the lookup in the write-ahead log to be read, got %+v", last)
+	}
+}
+
+func TestReadVocab_NotSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vocab.db")
+	if err := os.WriteFile(path, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kindle.ReadVocab(path); !errors.Is(err, kindle.ErrNotSQLite) {
+		t.Errorf("Expected ErrNotSQLite, got %v", err)
+	}
+}
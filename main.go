@@ -68,10 +68,16 @@ func newApplication(cfg *Config) *application {
 func (a *application) registerCommands() *cli.App {
 	a.app.Commands = []*cli.Command{
 		cmd.NewTTSCommand(a.config.APIKeys.OpenAI, a.config.hakiDir),
-		cmd.NewVocabCommand(a.config.APIKeys.OpenAI, a.config.hakiDir),
-		cmd.NewTopicCommand(a.config.APIKeys.OpenAI, a.config.hakiDir),
+		cmd.NewVocabCommand(a.config.APIKeys.OpenAI, a.config.hakiDir, a.config.Command("vocab"), a.config.DeckProfiles),
+		cmd.NewTopicCommand(a.config.APIKeys.OpenAI, a.config.hakiDir, a.config.Command("topic"), a.config.DeckProfiles),
+		cmd.NewFileCommand(a.config.APIKeys.OpenAI, a.config.hakiDir, a.config.Command("file"), a.config.DeckProfiles),
+		cmd.NewSyncCommand(a.config.APIKeys.OpenAI, a.config.hakiDir, a.config.Command("obsidian"), a.config.DeckProfiles),
+		cmd.NewImportCommand(a.config.APIKeys.OpenAI, a.config.hakiDir, a.config.Command, a.config.DeckProfiles),
 		cmd.NewImageCommand(a.config.APIKeys.OpenAI, a.config.hakiDir),
 		cmd.NewCardTestCommand(a.config.APIKeys.OpenAI),
+		cmd.NewHistoryCommand(a.config.hakiDir),
+		cmd.NewUndoCommand(a.config.hakiDir),
+		cmd.NewRoutesCommand(a.config.hakiDir),
 	}
 	return a.app
 }
@@ -171,5 +177,9 @@ func getHakiDirPath() (string, error) {
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		return "", err
 	}
+	promptsDir := filepath.Join(hakiDir, "prompts")
+	if err := os.MkdirAll(promptsDir, 0755); err != nil {
+		return "", err
+	}
 	return hakiDir, nil
 }
@@ -0,0 +1,219 @@
+// Package obsidian finds the notes of an Obsidian vault that are meant for Anki and keeps track of what has been
+// synced from them.
+//
+// The sync state of a note lives in its own frontmatter, under the haki key, as a single line of JSON (which is
+// also valid YAML). It records the deck the note goes in and, for every section, a hash of its content and the
+// ids of the Anki notes made from it, so later syncs only touch sections that changed.
+package obsidian
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/netr/haki/document"
+)
+
+// StateKey is the frontmatter key the sync state is stored under.
+const StateKey = "haki"
+
+// State is what has been synced from a note.
+type State struct {
+	Deck     string         `json:"deck,omitempty"`
+	Sections []SectionState `json:"sections"`
+}
+
+// SectionState is what has been synced from one section of a note.
+type SectionState struct {
+	Heading string    `json:"heading"`
+	Hash    string    `json:"hash"`
+	NoteIDs []float64 `json:"notes"`
+}
+
+// Take removes and returns the state of the first section with the given heading.
+func (s *State) Take(heading string) (SectionState, bool) {
+	i := slices.IndexFunc(s.Sections, func(ss SectionState) bool { return ss.Heading == heading })
+	if i < 0 {
+		return SectionState{}, false
+	}
+	ss := s.Sections[i]
+	s.Sections = slices.Delete(s.Sections, i, i+1)
+	return ss, true
+}
+
+// Hash identifies the content of a section. It changes whenever the heading or the text does.
+func Hash(s document.Section) string {
+	sum := sha256.Sum256([]byte(s.Heading() + "\n" + s.Body))
+	return hex.EncodeToString(sum[:8])
+}
+
+// frontMatter returns the lines of the note's frontmatter and the rest of the note. ok is false when the note
+// has no frontmatter.
+func frontMatter(text string) (lines []string, rest string, ok bool) {
+	if !strings.HasPrefix(text, "---\n") && !strings.HasPrefix(text, "---\r\n") {
+		return nil, text, false
+	}
+	lines = strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], "\r") == "---" {
+			return lines[1:i], strings.Join(lines[i+1:], "\n"), true
+		}
+	}
+	return nil, text, false
+}
+
+// ReadState returns the sync state stored in the note's frontmatter. A note that was never synced has an empty state.
+func ReadState(text string) (State, error) {
+	var state State
+	lines, _, _ := frontMatter(text)
+	for _, line := range lines {
+		value, ok := strings.CutPrefix(strings.TrimRight(line, "\r"), StateKey+":")
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(value)), &state); err != nil {
+			return State{}, fmt.Errorf("read state: %w", err)
+		}
+	}
+	return state, nil
+}
+
+// WriteState returns the note with the sync state stored in its frontmatter, adding frontmatter if it has none.
+// The rest of the frontmatter is left as it is.
+func WriteState(text string, state State) (string, error) {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return "", fmt.Errorf("write state: %w", err)
+	}
+	entry := StateKey + ": " + string(value)
+
+	lines, rest, ok := frontMatter(text)
+	if !ok {
+		return "---\n" + entry + "\n---\n" + text, nil
+	}
+	i := slices.IndexFunc(lines, func(l string) bool { return strings.HasPrefix(l, StateKey+":") })
+	if i < 0 {
+		lines = append(lines, entry)
+	} else {
+		lines[i] = entry
+	}
+	return "---\n" + strings.Join(lines, "\n") + "\n---\n" + rest, nil
+}
+
+var (
+	inlineTagRegex = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]+)`)
+	listItemRegex  = regexp.MustCompile(`^\s*-\s+(.+)$`)
+)
+
+// Tags returns the tags of a note: those listed under tags in its frontmatter and the #tags in its text.
+// Tags in code blocks don't count.
+func Tags(text string) []string {
+	var tags []string
+	add := func(tag string) {
+		tag = strings.TrimPrefix(strings.Trim(strings.TrimSpace(tag), `"'`), "#")
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	lines, rest, _ := frontMatter(text)
+	inTags := false
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if inTags {
+			if m := listItemRegex.FindStringSubmatch(line); m != nil {
+				add(m[1])
+				continue
+			}
+			inTags = false
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || (key != "tags" && key != "tag") {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), "[]")
+		if value == "" {
+			inTags = true
+			continue
+		}
+		for _, tag := range strings.Split(value, ",") {
+			add(tag)
+		}
+	}
+
+	fenced := false
+	for _, line := range strings.Split(rest, "\n") {
+		if t := strings.TrimSpace(line); strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			continue
+		}
+		for _, m := range inlineTagRegex.FindAllStringSubmatch(line, -1) {
+			add(m[1])
+		}
+	}
+	return tags
+}
+
+// HasTag reports whether the note is tagged with tag or one of its nested tags, ignoring case.
+func HasTag(text, tag string) bool {
+	for _, t := range Tags(text) {
+		if strings.EqualFold(t, tag) || strings.HasPrefix(strings.ToLower(t), strings.ToLower(tag)+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the notes in the vault meant for Anki: those tagged with tag and those in folder. Paths are
+// relative to the vault. Hidden folders, such as .obsidian and .trash, are skipped.
+func Find(vault, tag, folder string) ([]string, error) {
+	folder = strings.Trim(filepath.ToSlash(folder), "/")
+	var notes []string
+	err := filepath.WalkDir(vault, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != vault && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.EqualFold(filepath.Ext(path), ".md") {
+			return nil
+		}
+		rel, err := filepath.Rel(vault, path)
+		if err != nil {
+			return err
+		}
+		if folder != "" && strings.HasPrefix(filepath.ToSlash(rel), folder+"/") {
+			notes = append(notes, rel)
+			return nil
+		}
+		if tag == "" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if HasTag(string(data), tag) {
+			notes = append(notes, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find notes: %w", err)
+	}
+	return notes, nil
+}
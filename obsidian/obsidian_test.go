@@ -0,0 +1,129 @@
+package obsidian_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/netr/haki/document"
+	"github.com/netr/haki/obsidian"
+)
+
+func TestTags(t *testing.T) {
+	tests := map[string][]string{
+		"---\ntags: [anki, go]\n---\nText":                           {"anki", "go"},
+		"---\ntags:\n  - \"anki/go\"\n  - tcp\ntitle: x\n---\n":      {"anki/go", "tcp"},
+		"---\ntag: anki\n---\n# Heading\nSee #tcp/ip and url.com/#x": {"anki", "tcp/ip"},
+		"```\n#not-a-tag\n```\n#real":                                {"real"},
+	}
+	for text, expected := range tests {
+		if actual := obsidian.Tags(text); !slices.Equal(actual, expected) {
+			t.Errorf("Tags(%q) = %v, want %v", text, actual, expected)
+		}
+	}
+
+	if !obsidian.HasTag("#Anki/networking", "anki") {
+		t.Error("Expected nested tags to match their parent")
+	}
+	if obsidian.HasTag("#ankify", "anki") {
+		t.Error("Expected tags that only start with the tag not to match")
+	}
+}
+
+func TestState(t *testing.T) {
+	text := "---\ntitle: TCP\n---\n# TCP\n\nReliable transport."
+
+	state, err := obsidian.ReadState(text)
+	if err != nil {
+		t.Fatalf("ReadState: %v", err)
+	}
+	if len(state.Sections) != 0 {
+		t.Fatalf("Expected an empty state, got %+v", state)
+	}
+
+	state = obsidian.State{
+		Deck:     "Haki::Networking",
+		Sections: []obsidian.SectionState{{Heading: "TCP", Hash: "abc", NoteIDs: []float64{1700000000000}}},
+	}
+	written, err := obsidian.WriteState(text, state)
+	if err != nil {
+		t.Fatalf("WriteState: %v", err)
+	}
+	expected := "---\ntitle: TCP\nhaki: {\"deck\":\"Haki::Networking\",\"sections\":[{\"heading\":\"TCP\",\"hash\":\"abc\",\"notes\":[1700000000000]}]}\n---\n# TCP\n\nReliable transport."
+	if written != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, written)
+	}
+
+	state.Sections[0].Hash = "def"
+	rewritten, err := obsidian.WriteState(written, state)
+	if err != nil {
+		t.Fatalf("WriteState: %v", err)
+	}
+	read, err := obsidian.ReadState(rewritten)
+	if err != nil {
+		t.Fatalf("ReadState: %v", err)
+	}
+	if read.Deck != state.Deck || len(read.Sections) != 1 || read.Sections[0].Hash != "def" {
+		t.Errorf("Expected the state to be replaced, got %+v", read)
+	}
+
+	added, _ := obsidian.WriteState("# TCP", obsidian.State{})
+	if added != "---\nhaki: {\"sections\":null}\n---\n# TCP" {
+		t.Errorf("Expected frontmatter to be added, got %q", added)
+	}
+}
+
+func TestState_Take(t *testing.T) {
+	state := obsidian.State{Sections: []obsidian.SectionState{{Heading: "A", Hash: "1"}, {Heading: "B", Hash: "2"}}}
+	if ss, ok := state.Take("B"); !ok || ss.Hash != "2" {
+		t.Errorf("Expected to take B, got %+v", ss)
+	}
+	if _, ok := state.Take("B"); ok {
+		t.Error("Expected B to be taken only once")
+	}
+	if len(state.Sections) != 1 {
+		t.Errorf("Expected one section left, got %+v", state.Sections)
+	}
+}
+
+func TestHash(t *testing.T) {
+	a := document.Section{Headings: []string{"TCP"}, Body: "Reliable."}
+	b := document.Section{Headings: []string{"UDP"}, Body: "Reliable."}
+	if obsidian.Hash(a) == obsidian.Hash(b) {
+		t.Error("Expected the heading to change the hash")
+	}
+	if obsidian.Hash(a) != obsidian.Hash(a) {
+		t.Error("Expected the hash to be stable")
+	}
+}
+
+func TestFind(t *testing.T) {
+	vault := t.TempDir()
+	files := map[string]string{
+		"Anki/tcp.md":        "# TCP",
+		"inbox/udp.md":       "---\ntags: [anki]\n---\n# UDP",
+		"inbox/ip.md":        "# IP",
+		".obsidian/anki.md":  "#anki",
+		"Anki/image.png":     "",
+		"journal/2024-01.md": "Learned about #anki/go today",
+	}
+	for name, content := range files {
+		path := filepath.Join(vault, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notes, err := obsidian.Find(vault, "anki", "Anki")
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	expected := []string{filepath.Join("Anki", "tcp.md"), filepath.Join("inbox", "udp.md"), filepath.Join("journal", "2024-01.md")}
+	if !slices.Equal(notes, expected) {
+		t.Errorf("Expected %v, got %v", expected, notes)
+	}
+}
@@ -0,0 +1,239 @@
+// Package prompt loads the versioned text/template prompts used to generate Anki cards.
+//
+// Templates are looked up by name in the user's prompt directory first, then in the defaults embedded in haki.
+// A template declares its version in a leading comment: {{/* version: 2 */ -}}
+package prompt
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// DefaultName is the name of the prompt used when none is configured.
+const DefaultName = "default"
+
+const (
+	fileExt          = ".tmpl"
+	unversioned      = "0"
+	embeddedPrompts  = "templates"
+	maxVersionLength = 32
+)
+
+var (
+	ErrPromptNotFound    = errors.New("prompt not found")
+	ErrInvalidPromptName = errors.New("invalid prompt name")
+	ErrInvalidLevel      = errors.New("invalid level, expected beginner, intermediate or expert")
+	ErrInvalidStyle      = errors.New("invalid style, expected atomic or comprehensive")
+)
+
+//go:embed templates/*.tmpl
+var defaults embed.FS
+
+var versionRegex = regexp.MustCompile(`^\{\{-?\s*/\*\s*version:\s*(\S+)\s*\*/\s*-?\}\}`)
+
+// Data holds the variables available to prompt templates.
+type Data struct {
+	// DeckName is the deck the cards are generated for.
+	DeckName string
+	// Tags are the tags already used in the deck.
+	Tags []string
+	// Language is the language the cards should be written in.
+	Language string
+	// Count is the number of cards to create. Zero lets the model decide.
+	Count int
+	// MaxCards is the most cards to create when Count is zero. Zero means no limit.
+	MaxCards int
+	// Level is the audience the cards are written for. Empty lets the model decide.
+	Level Level
+	// Style is how much each card covers. Empty lets the model decide.
+	Style Style
+	// Grounded restricts the cards to facts stated in the user's text, each citing a verbatim quote.
+	Grounded bool
+	// Examples are existing cards from the deck, used as style examples.
+	Examples []Example
+}
+
+// Level is the audience cards are written for.
+type Level string
+
+const (
+	LevelBeginner     Level = "beginner"
+	LevelIntermediate Level = "intermediate"
+	LevelExpert       Level = "expert"
+)
+
+// ParseLevel parses a level name. An empty name is allowed and lets the model decide.
+func ParseLevel(s string) (Level, error) {
+	switch l := Level(strings.ToLower(strings.TrimSpace(s))); l {
+	case "", LevelBeginner, LevelIntermediate, LevelExpert:
+		return l, nil
+	default:
+		return "", fmt.Errorf("%s: %w", s, ErrInvalidLevel)
+	}
+}
+
+// Style is how much a single card covers.
+type Style string
+
+const (
+	// StyleAtomic asks for one fact per card.
+	StyleAtomic Style = "atomic"
+	// StyleComprehensive asks for fewer cards that each explain a concept in depth.
+	StyleComprehensive Style = "comprehensive"
+)
+
+// ParseStyle parses a style name. An empty name is allowed and lets the model decide.
+func ParseStyle(s string) (Style, error) {
+	switch st := Style(strings.ToLower(strings.TrimSpace(s))); st {
+	case "", StyleAtomic, StyleComprehensive:
+		return st, nil
+	default:
+		return "", fmt.Errorf("%s: %w", s, ErrInvalidStyle)
+	}
+}
+
+// Example is an existing card shown to the model as a style example.
+type Example struct {
+	Front string
+	Back  string
+}
+
+// charsPerToken is a rough average for English text, close enough to budget examples without a tokenizer.
+const charsPerToken = 4
+
+// EstimateTokens roughly estimates the number of tokens in text.
+func EstimateTokens(text string) int {
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
+
+// FitExamples returns the examples, in order, that fit within the token budget.
+// Examples too large to fit in the remaining budget are skipped rather than truncated.
+func FitExamples(examples []Example, budget int) []Example {
+	var fitted []Example
+	for _, e := range examples {
+		cost := EstimateTokens(e.Front) + EstimateTokens(e.Back)
+		if cost > budget {
+			continue
+		}
+		budget -= cost
+		fitted = append(fitted, e)
+	}
+	return fitted
+}
+
+// Template is a named, versioned prompt template.
+type Template struct {
+	Name    string
+	Version string
+	tmpl    *template.Template
+}
+
+// ID identifies the exact prompt a card was generated with, e.g. default@1.
+func (t *Template) ID() string {
+	return t.Name + "@" + t.Version
+}
+
+// Render executes the template with the given data.
+func (t *Template) Render(data Data) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render prompt (%s): %w", t.ID(), err)
+	}
+	return buf.String(), nil
+}
+
+// Library finds prompt templates in a directory, falling back to the embedded defaults.
+type Library struct {
+	dir string
+}
+
+// NewLibrary creates a Library that loads user templates from dir. An empty dir only uses the embedded defaults.
+func NewLibrary(dir string) *Library {
+	return &Library{dir: dir}
+}
+
+// Dir returns the directory user templates are loaded from.
+func (l *Library) Dir() string {
+	return l.dir
+}
+
+// Load returns the template with the given name.
+func (l *Library) Load(name string) (*Template, error) {
+	if name == "" {
+		name = DefaultName
+	}
+	if strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("%s: %w", name, ErrInvalidPromptName)
+	}
+
+	data, err := l.readUserTemplate(name)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = defaults.ReadFile(embeddedPrompts + "/" + name + fileExt)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", name, ErrPromptNotFound)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load prompt (%s): %w", name, err)
+	}
+	return Parse(name, string(data))
+}
+
+// readUserTemplate reads a template from the user's prompt directory. A library without one has no user templates.
+func (l *Library) readUserTemplate(name string) ([]byte, error) {
+	if l.dir == "" {
+		return nil, os.ErrNotExist
+	}
+	return os.ReadFile(filepath.Join(l.dir, name+fileExt))
+}
+
+// Names returns the names of every available template, user and embedded.
+func (l *Library) Names() ([]string, error) {
+	var names []string
+	embedded, err := fs.Glob(defaults, embeddedPrompts+"/*"+fileExt)
+	if err != nil {
+		return nil, fmt.Errorf("list prompts: %w", err)
+	}
+	var user []string
+	if l.dir != "" {
+		user, err = filepath.Glob(filepath.Join(l.dir, "*"+fileExt))
+		if err != nil {
+			return nil, fmt.Errorf("list prompts: %w", err)
+		}
+	}
+
+	for _, p := range append(embedded, user...) {
+		name := strings.TrimSuffix(filepath.Base(p), fileExt)
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// Parse parses the text of a prompt template.
+func Parse(name, text string) (*Template, error) {
+	version := unversioned
+	if m := versionRegex.FindStringSubmatch(text); m != nil && len(m[1]) <= maxVersionLength {
+		version = m[1]
+	}
+
+	tmpl, err := template.New(name).
+		Funcs(template.FuncMap{"join": strings.Join}).
+		Option("missingkey=error").
+		Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse prompt (%s): %w", name, err)
+	}
+	return &Template{Name: name, Version: version, tmpl: tmpl}, nil
+}
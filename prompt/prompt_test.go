@@ -0,0 +1,177 @@
+package prompt_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/netr/haki/prompt"
+)
+
+func TestLibrary_LoadEmbeddedDefault(t *testing.T) {
+	lib := prompt.NewLibrary(t.TempDir())
+
+	tmpl, err := lib.Load("")
+	if err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+	if tmpl.Name != prompt.DefaultName {
+		t.Errorf("Expected name to be '%s', got '%s'", prompt.DefaultName, tmpl.Name)
+	}
+	if tmpl.Version == "0" {
+		t.Error("Expected the default prompt to be versioned")
+	}
+
+	text, err := tmpl.Render(prompt.Data{
+		DeckName: "Haki::Go",
+		Tags:     []string{"go", "concurrency"},
+		Count:    3,
+		Examples: []prompt.Example{{Front: "What is a goroutine?", Back: "A lightweight thread."}},
+	})
+	if err != nil {
+		t.Fatalf("Render() returned an error: %v", err)
+	}
+	for _, want := range []string{`"Haki::Go"`, "go, concurrency", "exactly 3 card(s)", "{{c1::Paris}}", "<Front>What is a goroutine?</Front>"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected rendered prompt to contain %q", want)
+		}
+	}
+	if strings.HasPrefix(text, "\n") {
+		t.Error("Expected the version comment to be trimmed from the rendered prompt")
+	}
+}
+
+func TestLibrary_LoadUserTemplateOverridesDefault(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "default.tmpl"), []byte("{{/* version: 7 */ -}}\nCards for {{.DeckName}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := prompt.NewLibrary(dir).Load("default")
+	if err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+	if tmpl.ID() != "default@7" {
+		t.Errorf("Expected ID to be 'default@7', got '%s'", tmpl.ID())
+	}
+
+	text, err := tmpl.Render(prompt.Data{DeckName: "Math"})
+	if err != nil {
+		t.Fatalf("Render() returned an error: %v", err)
+	}
+	if text != "Cards for Math" {
+		t.Errorf("Expected 'Cards for Math', got '%s'", text)
+	}
+}
+
+func TestLibrary_LoadErrors(t *testing.T) {
+	lib := prompt.NewLibrary(t.TempDir())
+
+	if _, err := lib.Load("missing"); !errors.Is(err, prompt.ErrPromptNotFound) {
+		t.Errorf("Expected ErrPromptNotFound, got %v", err)
+	}
+	if _, err := lib.Load("../config"); !errors.Is(err, prompt.ErrInvalidPromptName) {
+		t.Errorf("Expected ErrInvalidPromptName, got %v", err)
+	}
+}
+
+func TestParse_Unversioned(t *testing.T) {
+	tmpl, err := prompt.Parse("plain", "Make cards.")
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+	if tmpl.Version != "0" {
+		t.Errorf("Expected version to be '0', got '%s'", tmpl.Version)
+	}
+}
+
+func TestLibrary_Names(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "math.tmpl"), []byte("math"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := prompt.NewLibrary(dir).Names()
+	if err != nil {
+		t.Fatalf("Names() returned an error: %v", err)
+	}
+	if strings.Join(names, ",") != "default,math" {
+		t.Errorf("Expected [default math], got %v", names)
+	}
+}
+
+func TestFitExamples(t *testing.T) {
+	examples := []prompt.Example{
+		{Front: "1234", Back: "1234"},                    // 2 tokens
+		{Front: strings.Repeat("x", 40), Back: ""},       // 10 tokens
+		{Front: "12345678", Back: "12345678"},            // 4 tokens
+		{Front: strings.Repeat("y", 400), Back: "large"}, // 102 tokens
+	}
+
+	fitted := prompt.FitExamples(examples, 8)
+	if len(fitted) != 2 {
+		t.Fatalf("Expected 2 examples to fit, got %d", len(fitted))
+	}
+	if fitted[0].Front != "1234" || fitted[1].Front != "12345678" {
+		t.Errorf("Expected examples to keep their order and skip the ones that don't fit, got %v", fitted)
+	}
+	if len(prompt.FitExamples(examples, 0)) != 0 {
+		t.Error("Expected no examples to fit in a zero budget")
+	}
+}
+
+func TestLibrary_LoadRendersLevelAndStyle(t *testing.T) {
+	tmpl, err := prompt.NewLibrary("").Load(prompt.DefaultName)
+	if err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+
+	text, err := tmpl.Render(prompt.Data{MaxCards: 4, Level: prompt.LevelExpert, Style: prompt.StyleAtomic, Grounded: true})
+	if err != nil {
+		t.Fatalf("Render() returned an error: %v", err)
+	}
+	for _, want := range []string{"at most 4 card(s)", "writes for experts", "every card atomic", "copied verbatim"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected rendered prompt to contain %q", want)
+		}
+	}
+	if strings.Contains(text, "writes for beginners") {
+		t.Error("Expected only the expert level to be rendered")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    prompt.Level
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: "Beginner", want: prompt.LevelBeginner},
+		{in: " expert ", want: prompt.LevelExpert},
+		{in: "guru", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := prompt.ParseLevel(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, prompt.ErrInvalidLevel) {
+				t.Errorf("ParseLevel(%q) error = %v, want ErrInvalidLevel", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseStyle(t *testing.T) {
+	if got, err := prompt.ParseStyle("Comprehensive"); err != nil || got != prompt.StyleComprehensive {
+		t.Errorf("ParseStyle() = %q, %v, want %q", got, err, prompt.StyleComprehensive)
+	}
+	if _, err := prompt.ParseStyle("verbose"); !errors.Is(err, prompt.ErrInvalidStyle) {
+		t.Errorf("ParseStyle() error = %v, want ErrInvalidStyle", err)
+	}
+}
@@ -0,0 +1,311 @@
+// Package subtitles reads SRT and WebVTT subtitles and mines them for sentences with a single unknown word,
+// the sentences a language learner can understand from context.
+package subtitles
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrNoCues           = errors.New("subtitles have no cues")
+	ErrInvalidTimestamp = errors.New("invalid cue timestamp")
+)
+
+// Cue is a subtitle shown from Start to End.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	// Text is the cue's text on a single line, without formatting tags.
+	Text string
+}
+
+var (
+	blockRegex     = regexp.MustCompile(`\n\s*\n`)
+	tagRegex       = regexp.MustCompile(`<[^>]*>|\{[^}]*\}`)
+	dashRegex      = regexp.MustCompile(`^[-‐–—]\s*`)
+	timestampRegex = regexp.MustCompile(`^(?:(\d+):)?(\d{1,2}):(\d{2})[,.](\d{1,3})$`)
+)
+
+// Parse parses SRT or WebVTT subtitles. Blocks without a timing line, such as WebVTT's header, notes and
+// styles, are skipped, as are cues without text.
+func Parse(data []byte) ([]Cue, error) {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var cues []Cue
+	for _, block := range blockRegex.Split(text, -1) {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			continue
+		}
+
+		from, to, _ := strings.Cut(lines[timing], "-->")
+		start, err := parseTimestamp(from)
+		if err != nil {
+			return nil, err
+		}
+		// WebVTT cue settings follow the end time.
+		fields := strings.Fields(to)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%s: %w", lines[timing], ErrInvalidTimestamp)
+		}
+		end, err := parseTimestamp(fields[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if cueText := cleanText(lines[timing+1:]); cueText != "" {
+			cues = append(cues, Cue{Start: start, End: end, Text: cueText})
+		}
+	}
+	if len(cues) == 0 {
+		return nil, ErrNoCues
+	}
+	return cues, nil
+}
+
+// parseTimestamp parses timestamps like 01:02:03,456 (SRT) and 02:03.456 (WebVTT).
+func parseTimestamp(s string) (time.Duration, error) {
+	m := timestampRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("%s: %w", strings.TrimSpace(s), ErrInvalidTimestamp)
+	}
+	hours, _ := strconv.Atoi(m[1])
+	minutes, _ := strconv.Atoi(m[2])
+	seconds, _ := strconv.Atoi(m[3])
+	millis, _ := strconv.Atoi((m[4] + "00")[:3])
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second + time.Duration(millis)*time.Millisecond, nil
+}
+
+// cleanText joins a cue's lines, dropping formatting tags and the dashes that mark a change of speaker.
+func cleanText(lines []string) string {
+	var parts []string
+	for _, line := range lines {
+		line = tagRegex.ReplaceAllString(line, "")
+		line = html.UnescapeString(line)
+		line = strings.TrimSpace(dashRegex.ReplaceAllString(strings.TrimSpace(line), ""))
+		if line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+}
+
+// FormatTimestamp formats d as HH:MM:SS.
+func FormatTimestamp(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+// Sentence is a sentence of dialogue, which may span several cues.
+type Sentence struct {
+	Text string
+	// Start is when the first of its cues is shown and End when the last one is hidden.
+	Start time.Duration
+	End   time.Duration
+}
+
+var sentenceEndRegex = regexp.MustCompile(`[.!?…]+["'»”’)\]]*(\s+|$)`)
+
+// Sentences splits the dialogue of the cues into sentences. A sentence that doesn't end in its cue continues
+// into the next one.
+func Sentences(cues []Cue) []Sentence {
+	var sentences []Sentence
+	var pending *Sentence
+	for _, c := range cues {
+		text := c.Text
+		for text != "" {
+			if pending == nil {
+				pending = &Sentence{Start: c.Start}
+			}
+			pending.End = c.End
+
+			loc := sentenceEndRegex.FindStringIndex(text)
+			if loc == nil {
+				pending.Text = strings.TrimSpace(pending.Text + " " + text)
+				break
+			}
+			pending.Text = strings.TrimSpace(pending.Text + " " + text[:loc[1]])
+			sentences = append(sentences, *pending)
+			pending = nil
+			text = text[loc[1]:]
+		}
+	}
+	if pending != nil {
+		sentences = append(sentences, *pending)
+	}
+	return sentences
+}
+
+// token is a word in a text and where it is.
+type token struct {
+	start, end int
+	word       string
+}
+
+// tokens finds the words of text: runs of letters, joined by apostrophes or hyphens.
+func tokens(text string) []token {
+	var found []token
+	start := -1
+	runes := []rune(text)
+	offset := 0
+	offsets := make([]int, len(runes)+1)
+	for i, r := range runes {
+		offsets[i] = offset
+		offset += len(string(r))
+	}
+	offsets[len(runes)] = offset
+
+	isWord := func(i int) bool {
+		return unicode.IsLetter(runes[i]) || unicode.IsMark(runes[i])
+	}
+	for i := 0; i <= len(runes); i++ {
+		inWord := i < len(runes) && (isWord(i) ||
+			start >= 0 && strings.ContainsRune("'’-", runes[i]) && i+1 < len(runes) && isWord(i+1))
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			word := text[offsets[start]:offsets[i]]
+			found = append(found, token{start: offsets[start], end: offsets[i], word: strings.ToLower(word)})
+			start = -1
+		}
+	}
+	return found
+}
+
+// Words returns the words of text, lowercased.
+func Words(text string) []string {
+	var words []string
+	for _, t := range tokens(text) {
+		words = append(words, t.word)
+	}
+	return words
+}
+
+// Emphasize returns text as HTML with every occurrence of word in bold.
+func Emphasize(text, word string) string {
+	var b strings.Builder
+	last := 0
+	for _, t := range tokens(text) {
+		if t.word != strings.ToLower(word) {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[last:t.start]))
+		b.WriteString("<b>" + html.EscapeString(text[t.start:t.end]) + "</b>")
+		last = t.end
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+// KnownWords is a set of lowercased words the learner already knows.
+type KnownWords map[string]bool
+
+// ReadKnownWords reads a known-words list with a word per line. Blank lines and lines starting with # are
+// skipped, and only the first tab-separated column is read, so word lists exported from Anki work too.
+func ReadKnownWords(r io.Reader) (KnownWords, error) {
+	known := KnownWords{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		column, _, _ := strings.Cut(line, "\t")
+		for _, w := range Words(column) {
+			known[w] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read known words: %w", err)
+	}
+	return known, nil
+}
+
+// Candidate is a sentence to learn Word from.
+type Candidate struct {
+	Sentence Sentence
+	Word     string
+}
+
+// Key identifies the candidate by its word and sentence.
+func (c Candidate) Key() string {
+	sum := sha256.Sum256([]byte(c.Word + "\n" + c.Sentence.Text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Mine finds the sentences with exactly one unknown word, which may appear more than once. Each word is only
+// mined from the first sentence it appears in, and sentences of a single word are skipped as they give no
+// context.
+func Mine(sentences []Sentence, known KnownWords) []Candidate {
+	var candidates []Candidate
+	mined := map[string]bool{}
+	for _, s := range sentences {
+		words := Words(s.Text)
+		if len(words) < 2 {
+			continue
+		}
+		unknown := ""
+		single := true
+		for _, w := range words {
+			if known[w] || w == unknown {
+				continue
+			}
+			if unknown != "" {
+				single = false
+				break
+			}
+			unknown = w
+		}
+		if !single || unknown == "" || mined[unknown] {
+			continue
+		}
+		mined[unknown] = true
+		candidates = append(candidates, Candidate{Sentence: s, Word: unknown})
+	}
+	return candidates
+}
+
+var episodeRegex = regexp.MustCompile(`(?i)s(\d{1,2})[ ._-]?e(\d{1,3})`)
+
+// Episode names the episode a subtitle file is for from its file name, e.g. "The Show S01E02" for
+// The.Show.S01E02.1080p.srt. Without a season and episode, it is the file name without its extension.
+func Episode(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	loc := episodeRegex.FindStringSubmatchIndex(name)
+	if loc == nil {
+		return name
+	}
+	season, _ := strconv.Atoi(name[loc[2]:loc[3]])
+	episode, _ := strconv.Atoi(name[loc[4]:loc[5]])
+	show := strings.Join(strings.FieldsFunc(name[:loc[0]], func(r rune) bool {
+		return r == '.' || r == '_' || r == '-' || unicode.IsSpace(r)
+	}), " ")
+	code := fmt.Sprintf("S%02dE%02d", season, episode)
+	if show == "" {
+		return code
+	}
+	return show + " " + code
+}
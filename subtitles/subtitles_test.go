@@ -0,0 +1,155 @@
+package subtitles_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/netr/haki/subtitles"
+)
+
+const srt = "1\r\n" +
+	"00:00:01,000 --> 00:00:03,500\r\n" +
+	"<i>¿Dónde está</i>\r\n" +
+	"la biblioteca?\r\n" +
+	"\r\n" +
+	"2\r\n" +
+	"00:00:04,000 --> 00:00:06,000\r\n" +
+	"- No lo sé. - Está cerca\r\n" +
+	"\r\n" +
+	"3\r\n" +
+	"00:00:06,200 --> 00:00:08,000\r\n" +
+	"del mercado.\r\n"
+
+const vtt = "WEBVTT\n\n" +
+	"NOTE a comment\n\n" +
+	"intro\n" +
+	"00:01.000 --> 00:02.500 align:start\n" +
+	"<v Ana>Hola, amigo.</v>\n\n" +
+	"01:00:00.000 --> 01:00:01.000\n" +
+	"{\\an8}Adiós.\n"
+
+func TestParse_SRT(t *testing.T) {
+	cues, err := subtitles.Parse([]byte(srt))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(cues) != 3 {
+		t.Fatalf("Expected 3 cues, got %d", len(cues))
+	}
+	if cues[0].Text != "¿Dónde está la biblioteca?" {
+		t.Errorf("Expected cue lines joined without tags, got %q", cues[0].Text)
+	}
+	if cues[0].Start != time.Second || cues[0].End != 3500*time.Millisecond {
+		t.Errorf("Unexpected cue timing: %v --> %v", cues[0].Start, cues[0].End)
+	}
+	if cues[1].Text != "No lo sé. - Está cerca" {
+		t.Errorf("Expected the leading speaker dash to be dropped, got %q", cues[1].Text)
+	}
+}
+
+func TestParse_WebVTT(t *testing.T) {
+	cues, err := subtitles.Parse([]byte(vtt))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(cues) != 2 {
+		t.Fatalf("Expected 2 cues, got %d", len(cues))
+	}
+	if cues[0].Text != "Hola, amigo." || cues[0].End != 2500*time.Millisecond {
+		t.Errorf("Unexpected first cue: %+v", cues[0])
+	}
+	if cues[1].Text != "Adiós." || cues[1].Start != time.Hour {
+		t.Errorf("Unexpected second cue: %+v", cues[1])
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	if _, err := subtitles.Parse([]byte("WEBVTT\n\n")); !errors.Is(err, subtitles.ErrNoCues) {
+		t.Errorf("Expected ErrNoCues, got %v", err)
+	}
+	if _, err := subtitles.Parse([]byte("1\nsoon --> later\nHola\n")); !errors.Is(err, subtitles.ErrInvalidTimestamp) {
+		t.Errorf("Expected ErrInvalidTimestamp, got %v", err)
+	}
+}
+
+func TestSentences(t *testing.T) {
+	cues, err := subtitles.Parse([]byte(srt))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	sentences := subtitles.Sentences(cues)
+
+	var texts []string
+	for _, s := range sentences {
+		texts = append(texts, s.Text)
+	}
+	expected := "¿Dónde está la biblioteca?|No lo sé.|- Está cerca del mercado."
+	if strings.Join(texts, "|") != expected {
+		t.Fatalf("Expected %q, got %q", expected, strings.Join(texts, "|"))
+	}
+	last := sentences[2]
+	if last.Start != 4*time.Second || last.End != 8*time.Second {
+		t.Errorf("Expected a sentence spanning cues to span their timing, got %v --> %v", last.Start, last.End)
+	}
+}
+
+func TestMine(t *testing.T) {
+	known, err := subtitles.ReadKnownWords(strings.NewReader("# known\ndónde\nestá\tis\n\nla\nno lo\nsé\n"))
+	if err != nil {
+		t.Fatalf("ReadKnownWords: %v", err)
+	}
+	sentences := []subtitles.Sentence{
+		{Text: "¿Dónde está la biblioteca?"},
+		{Text: "Biblioteca, la biblioteca."},
+		{Text: "No lo sé, señor."},
+		{Text: "Biblioteca."},
+		{Text: "La casa está cerca del mercado."},
+		{Text: "¿Está la biblioteca, la BIBLIOTECA?"},
+	}
+
+	candidates := subtitles.Mine(sentences, known)
+	if len(candidates) != 2 {
+		t.Fatalf("Expected 2 candidates, got %+v", candidates)
+	}
+	if candidates[0].Word != "biblioteca" || candidates[0].Sentence.Text != "¿Dónde está la biblioteca?" {
+		t.Errorf("Expected biblioteca from its first sentence, got %+v", candidates[0])
+	}
+	if candidates[1].Word != "señor" {
+		t.Errorf("Expected señor, got %+v", candidates[1])
+	}
+}
+
+func TestWords(t *testing.T) {
+	words := subtitles.Words("¡Qu'est-ce que c'est, Pedro? 3 años…")
+	if strings.Join(words, ",") != "qu'est-ce,que,c'est,pedro,años" {
+		t.Errorf("Unexpected words: %v", words)
+	}
+}
+
+func TestEmphasize(t *testing.T) {
+	got := subtitles.Emphasize("Biblioteca & la biblioteca.", "biblioteca")
+	if got != "<b>Biblioteca</b> &amp; la <b>biblioteca</b>." {
+		t.Errorf("Unexpected emphasis: %q", got)
+	}
+}
+
+func TestEpisode(t *testing.T) {
+	tests := map[string]string{
+		"/tv/La.Casa.de.Papel.S01E02.1080p.srt": "La Casa de Papel S01E02",
+		"s2e10.vtt":                             "S02E10",
+		"movie.es.srt":                          "movie.es",
+	}
+	for path, expected := range tests {
+		if got := subtitles.Episode(path); got != expected {
+			t.Errorf("Episode(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	if got := subtitles.FormatTimestamp(time.Hour + 2*time.Minute + 3500*time.Millisecond); got != "01:02:03" {
+		t.Errorf("Expected 01:02:03, got %s", got)
+	}
+}